@@ -0,0 +1,73 @@
+package sexrt
+
+import (
+	"regexp"
+)
+
+// AnchorRegexps will make the regexps of the routes registered later match the full value
+// of the path segment, extension, host, query and header (e.g. "{id:\d+}" reject "x1"),
+// the whole regexp host (e.g. "{^\w+\.com$}") match the raw Host which may contain the port
+func (mux *Mux) AnchorRegexps(on bool) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.anchorRegexps = on
+}
+
+// isAnchored report whether the regexps of routes registered now are anchored
+func (mux *Mux) isAnchored() bool {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	return mux.anchorRegexps
+}
+
+// NewAnchoredMux will new a Mux which anchor the regexps of routes, see Mux.AnchorRegexps
+func NewAnchoredMux() *Mux {
+	mux := NewMux()
+	mux.AnchorRegexps(true)
+	return mux
+}
+
+// anchor compile the anchored regexps of this route, the original ones are kept for introspection
+func (rt *Route) anchor() {
+	rt.anchors = make(map[*regexp.Regexp]*regexp.Regexp)
+
+	add := func(slice []interface{}) {
+		for _, item := range slice {
+			switch item.(type) {
+			case *regexp.Regexp:
+				reg := item.(*regexp.Regexp)
+				rt.anchors[reg] = anchorRegexp(reg)
+
+			case *namedRegexp:
+				// the typed arguments are already anchored
+				if nr := item.(*namedRegexp); nr.conv == nil {
+					rt.anchors[nr.Regexp] = anchorRegexp(nr.Regexp)
+				}
+			}
+		}
+	}
+
+	add(rt.paths)
+	add(rt.exts)
+	add(rt.hosts)
+	for _, slice := range rt.querys {
+		add(slice)
+	}
+	for _, slice := range rt.headers {
+		add(slice)
+	}
+}
+
+func anchorRegexp(reg *regexp.Regexp) *regexp.Regexp {
+	return regexp.MustCompile(`^(?:` + reg.String() + `)$`)
+}
+
+// regexp return the anchored regexp if this route is anchored
+func (rt *Route) regexp(reg *regexp.Regexp) *regexp.Regexp {
+	if anchored, ok := rt.anchors[reg]; ok {
+		return anchored
+	}
+	return reg
+}
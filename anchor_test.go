@@ -0,0 +1,65 @@
+package sexrt
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMuxAnchorRegexps(t *testing.T) {
+	mux := NewAnchoredMux()
+
+	mux.NewRoute().Name("user").Path("user", `{id:\d+}`).Ext(`{json|html}`).Func(testWriteHandler("user"))
+	mux.NewRoute().Path("search").Query("q", `{q:\w+}`).Header("X-Token", `{[a-f0-9]+}`).Func(testWriteHandler("search"))
+	mux.NewRoute().Host(`{api\d*\.example\.com}`).Path("host").Func(testWriteHandler("host"))
+	mux.NewRoute().Path("ignore", `{^(?i)abc$}`).IgnoreHeaderCase().Func(testWriteHandler("ignore"))
+	mux.NewRoute().Path(`{id:\d+}`).Func(testWriteHandler("id"))
+
+	tests := []struct {
+		u      string
+		header map[string]string
+		status int
+	}{
+		{"/user/12.html", nil, 200},
+		{"/user/x1.html", nil, 404},
+		{"/user/1x.html", nil, 404},
+		{"/user/12.json", nil, 200},
+		{"/user/12.jsonp", nil, 404},
+		{"/search?q=go", map[string]string{"X-Token": "beef"}, 200},
+		{"/search?q=go+lang", map[string]string{"X-Token": "beef"}, 404},
+		{"/search?q=go", map[string]string{"X-Token": "beefy"}, 404},
+		{"http://api2.example.com/host", nil, 200},
+		{"http://api2.example.com.evil.org/host", nil, 404},
+		{"/ignore/ABC", nil, 200},
+		{"/1", nil, 200},
+		{"/x1", nil, 404},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.u, nil)
+		for k, v := range test.header {
+			req.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Fatalf("%s %v: status %d not equal %d", test.u, test.header, w.Code, test.status)
+		}
+	}
+
+	if _, err := mux.URL("user", map[string]string{"id": "x1"}); err == nil {
+		t.Fatal("unanchored argument built")
+	}
+	if s := mux.Routes()[0].String(); s != `/user/{id:\d+}.{json|html}` {
+		t.Fatal("original pattern not introspected:", s)
+	}
+
+	// not anchored by default
+	mux = NewMux()
+	mux.NewRoute().Path(`{id:\d+}`).Func(testHandler)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/x1", nil))
+	if w.Code != 200 {
+		t.Fatal("/x1: regexp anchored by default")
+	}
+}
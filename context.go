@@ -0,0 +1,54 @@
+package sexrt
+
+import (
+	"context"
+	"net/http"
+)
+
+// Match is the match result stored in the request context,
+// so that standard http.Handler can read the captures
+type Match struct {
+	Args     map[string]string // regexp arguments, same as Ctx.Args
+	Name     string            // name of the matched route
+	Template string            // path template of the matched route (e.g. "/user/{id:\d+}")
+}
+
+type matchContextKey struct{}
+
+// MatchFromRequest return the match result stored in the request context, nil if not exists
+func MatchFromRequest(r *http.Request) *Match {
+	m, _ := r.Context().Value(matchContextKey{}).(*Match)
+	return m
+}
+
+// ArgsFromRequest return the regexp arguments stored in the request context, nil if not exists
+func ArgsFromRequest(r *http.Request) map[string]string {
+	if m := MatchFromRequest(r); m != nil {
+		return m.Args
+	}
+	return nil
+}
+
+// withMatch store the match result into the request context of ctx
+func withMatch(ctx *Ctx) {
+	m := &Match{Args: ctx.Args}
+	if ctx.Route != nil {
+		m.Name, m.Template = ctx.Route.name, ctx.Route.pathTemplate()
+	}
+	ctx.R = ctx.R.WithContext(context.WithValue(ctx.R.Context(), matchContextKey{}, m))
+}
+
+// Handler register a standard http.Handler with the building route, same as Func,
+// the captures can be read by ArgsFromRequest
+func (rt *Route) Handler(h http.Handler) {
+	rt.Func(func(ctx *Ctx) error {
+		h.ServeHTTP(ctx.W, ctx.R)
+		return nil
+	})
+}
+
+// HandlerFunc register a standard http.HandlerFunc with the building route, same as Func,
+// the captures can be read by ArgsFromRequest
+func (rt *Route) HandlerFunc(fn http.HandlerFunc) {
+	rt.Handler(fn)
+}
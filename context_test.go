@@ -0,0 +1,52 @@
+package sexrt
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteHandler(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.NewRoute().Name("user").Path("user", `{name:\w+}`).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := MatchFromRequest(r)
+		fmt.Fprintf(w, "%s %s %s", m.Name, m.Template, ArgsFromRequest(r)["name"])
+	}))
+	mux.NewRoute().Path("func").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, len(ArgsFromRequest(r)))
+	})
+
+	// the standard middleware can read the captures
+	mux.Use(func(next func(*Ctx) error) func(*Ctx) error {
+		return func(ctx *Ctx) error {
+			if ArgsFromRequest(ctx.R) == nil {
+				t.Error("captures not stored in request context")
+			}
+			return next(ctx)
+		}
+	})
+
+	tests := []struct {
+		u    string
+		body string
+	}{
+		{"/user/jmjoy", `user /user/{name:\w+} jmjoy`},
+		{"/func", "0"},
+	}
+	for _, test := range tests {
+		testHTTPResponse("GET", srv.URL+test.u, "", func(body string, resp *http.Response) {
+			if body != test.body {
+				t.Fatalf("%s: body %q not equal %q", test.u, body, test.body)
+			}
+		})
+	}
+
+	if MatchFromRequest(httptest.NewRequest("GET", "/", nil)) != nil {
+		t.Fatal("match result of a request not served by Mux")
+	}
+}
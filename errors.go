@@ -0,0 +1,65 @@
+package sexrt
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HTTPError is an error carrying the HTTP status, return it from a handler
+// and the default error handler will write the status and the public message
+type HTTPError struct {
+	Status  int    // HTTP status code
+	Message string // public message written to the response, use the status text if empty
+	Err     error  // wrapped cause, not written to the response
+}
+
+// Error will new a HTTPError with the status and the public message
+func Error(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+// WrapError will new a HTTPError with the status, the public message and the wrapped cause
+func WrapError(status int, message string, err error) *HTTPError {
+	return &HTTPError{Status: status, Message: message, Err: err}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err == nil {
+		return e.publicMessage()
+	}
+	return e.publicMessage() + ": " + e.Err.Error()
+}
+
+// Unwrap return the wrapped cause
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Is report whether the target is a HTTPError with the same status,
+// so that errors.Is(err, sexrt.Error(404, "")) works
+func (e *HTTPError) Is(target error) bool {
+	t, ok := target.(*HTTPError)
+	return ok && t.Status == e.Status
+}
+
+func (e *HTTPError) publicMessage() string {
+	if e.Message == "" {
+		return http.StatusText(e.Status)
+	}
+	return e.Message
+}
+
+// defaultErrorHandler write the status and the public message of HTTPError,
+// the unknown errors become "500 Internal Server Error" and are logged
+func (mux *Mux) defaultErrorHandler(ctx *Ctx, err error) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = WrapError(http.StatusInternalServerError, "", err)
+	}
+
+	if httpErr.Status >= http.StatusInternalServerError {
+		mux.logError(ctx, err)
+	}
+
+	http.Error(ctx.W, httpErr.publicMessage(), httpErr.Status)
+}
@@ -0,0 +1,84 @@
+package sexrt
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	err := Error(404, "no such user")
+	if err.Error() != "no such user" {
+		t.Fatal("message not correct:", err.Error())
+	}
+	if Error(404, "").Error() != "Not Found" {
+		t.Fatal("status text not used")
+	}
+
+	wrapped := fmt.Errorf("find user: %w", WrapError(500, "", errHehe))
+	if !errors.Is(wrapped, errHehe) {
+		t.Fatal("cause not unwrapped")
+	}
+	if !errors.Is(wrapped, Error(500, "")) || errors.Is(wrapped, Error(404, "")) {
+		t.Fatal("status not compared")
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(wrapped, &httpErr) || httpErr.Status != 500 {
+		t.Fatal("HTTPError not found")
+	}
+	if httpErr.Error() != "Internal Server Error: hehe" {
+		t.Fatal("message not correct:", httpErr.Error())
+	}
+}
+
+func TestMuxErrorHandler(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.SetLogger(log.New(ioutil.Discard, "", 0))
+
+	mux.NewRoute().Path("user").Func(func(ctx *Ctx) error {
+		return Error(404, "no such user")
+	})
+	mux.NewRoute().Path("wrapped").Func(func(ctx *Ctx) error {
+		return fmt.Errorf("wrapped: %w", WrapError(403, "forbidden", errHehe))
+	})
+	mux.NewRoute().Path("unknown").Func(func(ctx *Ctx) error {
+		return errHehe
+	})
+
+	tests := []struct {
+		u      string
+		status int
+		body   string
+	}{
+		{"/user", 404, "no such user"},
+		{"/wrapped", 403, "forbidden"},
+		{"/unknown", 500, "Internal Server Error"},
+	}
+	for _, test := range tests {
+		testHTTPResponse("GET", srv.URL+test.u, "", func(body string, resp *http.Response) {
+			if resp.StatusCode != test.status || strings.TrimSpace(body) != test.body {
+				t.Fatalf("%s: response %d %q not correct", test.u, resp.StatusCode, body)
+			}
+		})
+	}
+
+	mux.HandleError(func(ctx *Ctx, err error) {
+		ctx.W.WriteHeader(418)
+		ctx.W.Write([]byte(err.Error()))
+	})
+	testHTTPResponse("GET", srv.URL+"/unknown", "", func(body string, resp *http.Response) {
+		if resp.StatusCode != 418 || body != "hehe" {
+			t.Fatal("user defined error handler not correct!")
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package sexrt
+
+// Subrouter return a new building route which inherit all the rules and middlewares of this route,
+// so that the common rules can be declared once and shared by the child routes.
+// The name isn't inherited, because the route name is unique.
+func (rt *Route) Subrouter() *Route {
+	newRoute := rt.clone()
+	newRoute.name = ""
+	return newRoute
+}
+
+// Group call fn with a subrouter of this route, and return this route for chaining
+func (rt *Route) Group(fn func(g *Route)) *Route {
+	fn(rt.Subrouter())
+	return rt
+}
+
+// NotFound set the not found handler for the unmatched requests under this route,
+// the paths of this route is used as a prefix, and the hosts, headers and predicates are also checked.
+// The handler with the longest prefix wins, and it is wrapped by the middlewares of this route.
+func (rt *Route) NotFound(fn routeHandler) *Route {
+	newRoute := rt.clone()
+	if rt.mux.isAnchored() {
+		newRoute.anchor()
+	}
+
+	rt.mux.mu.Lock()
+	defer rt.mux.mu.Unlock()
+
+	if rt.mux.rejectInvalid(newRoute) {
+		return rt
+	}
+
+	rt.mux.notFoundPool = append(rt.mux.notFoundPool, &routeEntry{
+		route: newRoute,
+		fn:    wrapMiddlewares(fn, newRoute.middlewares),
+	})
+	return rt
+}
+
+// findNotFound find the not found handler of the group with the longest prefix which match the request
+func (mux *Mux) findNotFound(ctx *Ctx) routeHandler {
+	// match the routes outside the lock, so that the user defined predicates can call back into the Mux,
+	// the pool is only appended
+	mux.mu.RLock()
+	pool := mux.notFoundPool
+	mux.mu.RUnlock()
+
+	var found *routeEntry
+	var foundState *matchState
+
+	for _, entry := range pool {
+		if found != nil && len(entry.route.paths) <= len(found.route.paths) {
+			continue
+		}
+
+		st := newMatchState()
+		st.reset(entry.route)
+		if isPrefixMatch(entry.route, ctx, st) {
+			found, foundState = entry, st
+		}
+	}
+
+	if found == nil {
+		return mux.muxNotFound(ctx)
+	}
+
+	foundState.commit(ctx)
+	return found.fn
+}
+
+// isPrefixMatch check the request is under a route, which means the hosts, headers and
+// user defined predicates of the route match, and the paths of the route match the prefix of request paths
+func isPrefixMatch(rt *Route, ctx *Ctx, st *matchState) bool {
+	r := ctx.R
+
+	if len(rt.hosts) > 0 && !isHostMatch(rt.hosts, ctx.host, st) {
+		return false
+	}
+
+	if len(rt.paths) > len(ctx.paths) {
+		return false
+	}
+	for i := range rt.paths {
+		if !isSingleMatch(rt.paths[i], ctx.paths[i], st) {
+			return false
+		}
+	}
+
+	if len(rt.headers) > 0 && !isHeaderMatch(rt, r.Header, st) {
+		return false
+	}
+
+	if len(rt.matchers) > 0 && !isMatchersMatch(rt.matchers, r, st) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,95 @@
+package sexrt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteSubrouter(t *testing.T) {
+	mux := NewMux()
+	api := mux.NewRoute().Path("api", "v1").Get()
+
+	users := api.Subrouter().Path("users")
+	if len(api.paths) != 2 || len(users.paths) != 3 || len(users.methods) != 1 {
+		t.Fatal("subrouter not inherit the rules of parent")
+	}
+	if checkPointerEqual(api.paths, users.paths) || checkPointerEqual(api.methods, users.methods) {
+		t.Fatal("subrouter not a new object")
+	}
+
+	// the name isn't inherited
+	mux.NewRoute().Name("admin").Path("admin").Group(func(g *Route) {
+		g.Subrouter().Path("a").Func(testWriteHandler("a"))
+		g.Subrouter().Path("b").Func(testWriteHandler("b"))
+	})
+	if err := mux.Validate(); err != nil {
+		t.Fatal("subrouters with the name of parent:", err)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/admin/b", nil))
+	if w.Body.String() != "b" {
+		t.Fatal("/admin/b: body not correct:", w.Body.String())
+	}
+}
+
+func TestRouteGroup(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleNotFound(testWriteHandler("notfound"))
+
+	mux.NewRoute().Path("api", `{version:^v\d+$}`).Use(testMiddleware("api")).Group(func(g *Route) {
+		g.NotFound(testWriteHandler("api notfound"))
+
+		g.Subrouter().Path("users").Func(testWriteHandler("users"))
+		g.Subrouter().Path("users", `{id:^\d+$}`).Func(func(ctx *Ctx) error {
+			_, err := ctx.W.Write([]byte(ctx.Args["version"] + ":" + ctx.Args["id"]))
+			return err
+		})
+
+		g.Path("admin").Header("X-Admin", "1").Group(func(g *Route) {
+			g.NotFound(testWriteHandler("admin notfound"))
+			g.Path("stats").Func(testWriteHandler("stats"))
+		})
+	})
+
+	tests := []struct {
+		u    string
+		body string
+	}{
+		{"/api/v1/users", "api(users)"},
+		{"/api/v2/users/123", "api(v2:123)"},
+		{"/api/v1/users/abc", "api(api notfound)"},
+		{"/api/v1/admin/stats", "api(api notfound)"},
+		{"/api/v1", "api(api notfound)"},
+		{"/api/x1/users", "notfound"},
+		{"/none", "notfound"},
+	}
+	for _, test := range tests {
+		testHTTPResponse("GET", srv.URL+test.u, "", func(body string, resp *http.Response) {
+			if body != test.body {
+				t.Fatalf("%s: body %q not equal %q", test.u, body, test.body)
+			}
+		})
+	}
+
+	header := map[string]string{"X-Admin": "1"}
+	tests = []struct {
+		u    string
+		body string
+	}{
+		{"/api/v1/admin/stats", "api(stats)"},
+		{"/api/v1/admin/none", "api(admin notfound)"},
+		{"/api/v1/none", "api(api notfound)"},
+	}
+	for _, test := range tests {
+		testHTTPResponseSetHeader("GET", srv.URL+test.u, "", header, func(body string, resp *http.Response) {
+			if body != test.body {
+				t.Fatalf("%s: body %q not equal %q", test.u, body, test.body)
+			}
+		})
+	}
+}
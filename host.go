@@ -0,0 +1,162 @@
+package sexrt
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// hostTemplate is a dot-segmented host pattern (e.g. "{tenant:[a-z]+}.example.com"),
+// it is matched case-insensitively, and the port is ignored unless the pattern includes one
+type hostTemplate struct {
+	Pattern string
+	*regexp.Regexp
+	parts []interface{} // the literal strings and the captures in order
+	port  bool          // the pattern includes a port
+}
+
+// TrustForwardedHost will make this Mux match the hosts of routes against the "X-Forwarded-Host"
+// header instead of the Host, turn it on only behind a proxy which set the header.
+// The header is only trusted from the proxies set by Mux.TrustProxies.
+func (mux *Mux) TrustForwardedHost(on bool) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.trustForwardedHost = on
+}
+
+// requestHost return the host used for matching the hosts of routes
+func (mux *Mux) requestHost(r *http.Request) string {
+	if mux.trustForwardedHost && len(mux.trustedProxies) > 0 && mux.isTrustedProxy(r) {
+		// the first one is the host requested by the client
+		host := r.Header.Get("X-Forwarded-Host")
+		if i := strings.IndexByte(host, ','); i >= 0 {
+			host = host[:i]
+		}
+		if host = strings.TrimSpace(host); host != "" {
+			return host
+		}
+	}
+	return r.Host
+}
+
+// parseHosts parse the host patterns and append them to the route, the pattern which is a whole regexp
+// (e.g. "{^\w+\.com$}") match the raw host, and the pattern with some captures is compiled to a hostTemplate
+func (rt *Route) parseHosts(s ...string) []interface{} {
+	hosts := rt.hosts
+
+	for i, str := range s {
+		if !isTemplate(str) {
+			item, err := parseString(str)
+			if err != nil {
+				rt.addError("Host", str, i, err)
+				continue
+			}
+			hosts = append(hosts, item)
+			continue
+		}
+
+		ht, err := compileHostTemplate(str)
+		if err != nil {
+			rt.addError("Host", str, i, err)
+			continue
+		}
+		hosts = append(hosts, ht)
+	}
+
+	return hosts
+}
+
+// isWholeBraces check the first "{" of a pattern is closed at the end
+func isWholeBraces(s string) bool {
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return false
+	}
+
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i == len(s)-1
+			}
+		}
+	}
+	return false
+}
+
+// compileHostTemplate compile a host pattern to a regexp, the literal parts are quoted,
+// and the regexp parts (e.g. "{tenant:[a-z]+}") become the subexpressions
+func compileHostTemplate(s string) (*hostTemplate, error) {
+	source, parts, err := parseTemplate(s, `[^.]+`)
+	if err != nil {
+		return nil, err
+	}
+
+	reg, err := regexp.Compile("(?i)^" + source + "$")
+	if err != nil {
+		// e.g. invalid capture name
+		return nil, err
+	}
+
+	ht := &hostTemplate{Pattern: s, Regexp: reg, parts: parts}
+	for _, part := range parts {
+		if literal, ok := part.(string); ok && strings.Contains(literal, ":") {
+			ht.port = true
+		}
+	}
+	return ht, nil
+}
+
+// isHostMatch check if one host pattern of the route match the request host,
+// the literal hosts and host templates ignore the case and the port unless they include one,
+// and the whole regexp patterns match the raw host
+func isHostMatch(rtHosts []interface{}, host string, st *matchState) bool {
+	hostname := strings.ToLower(strings.TrimSuffix(stripPort(host), "."))
+
+	for _, item := range rtHosts {
+		switch item.(type) {
+		case string:
+			s := item.(string)
+			if stripPort(s) != s {
+				if strings.EqualFold(s, host) {
+					return true
+				}
+			} else if strings.EqualFold(s, hostname) {
+				return true
+			}
+
+		case *hostTemplate:
+			ht := item.(*hostTemplate)
+			single := hostname
+			if ht.port {
+				single = strings.ToLower(host)
+			}
+			if isSingleMatch(ht, single, st) {
+				return true
+			}
+
+		default:
+			if isSingleMatch(item, host, st) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stripPort remove the port of a host (e.g. "localhost:8080", "[::1]:8080")
+func stripPort(host string) string {
+	i := strings.LastIndexByte(host, ':')
+	if i < 0 || strings.LastIndexByte(host, ']') > i {
+		return host
+	}
+	if strings.Count(host, ":") > 1 && !strings.HasPrefix(host, "[") {
+		// IPv6 address without port
+		return host
+	}
+	return host[:i]
+}
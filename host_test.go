@@ -0,0 +1,97 @@
+package sexrt
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMuxRouteHostTemplate(t *testing.T) {
+	mux := NewMux()
+
+	mux.NewRoute().Host("{tenant:[a-z]+}.example.com").Path("who").Func(func(ctx *Ctx) error {
+		_, err := ctx.W.Write([]byte("tenant:" + ctx.Args["tenant"]))
+		return err
+	})
+	mux.NewRoute().Host("localhost").Path("local").Func(testWriteHandler("local"))
+	mux.NewRoute().Host("{region}.{tenant:[a-z]+}.example.org").Path("region").Func(func(ctx *Ctx) error {
+		_, err := ctx.W.Write([]byte(ctx.Args["region"] + ":" + ctx.Args["tenant"]))
+		return err
+	})
+	mux.NewRoute().Host("admin.example.com:8443").Path("admin").Func(testWriteHandler("admin"))
+	mux.NewRoute().Host("{shard:int}.db.example.com").Path("shard").Func(func(ctx *Ctx) error {
+		if ctx.Int("shard") != 12 {
+			t.Error("typed host argument not converted")
+		}
+		return nil
+	})
+	mux.NewRoute().Host(`{^raw\.example\.com:\d+$}`).Path("raw").Func(testWriteHandler("raw"))
+
+	tests := []struct {
+		u      string
+		status int
+		body   string
+	}{
+		{"http://acme.example.com/who", 200, "tenant:acme"},
+		{"http://ACME.Example.com:8080/who", 200, "tenant:acme"},
+		{"http://acme.example.com./who", 200, "tenant:acme"},
+		{"http://a.b.example.com/who", 404, ""},
+		{"http://example.com/who", 404, ""},
+		{"http://localhost/local", 200, "local"},
+		{"http://eu-1.acme.example.org/region", 200, "eu-1:acme"},
+		{"http://eu.1.acme.example.org/region", 404, ""},
+		{"http://LocalHost:8080/local", 200, "local"},
+		{"http://admin.example.com:8443/admin", 200, "admin"},
+		{"http://admin.example.com/admin", 404, ""},
+		{"http://admin.example.com:8080/admin", 404, ""},
+		{"http://12.db.example.com/shard", 200, ""},
+		{"http://x.db.example.com/shard", 404, ""},
+		{"http://raw.example.com:80/raw", 200, "raw"},
+		{"http://raw.example.com/raw", 404, ""},
+		{"http://raw123/raw", 404, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.u, nil))
+		if w.Code != test.status || (test.status == 200 && w.Body.String() != test.body) {
+			t.Fatalf("%s: response %d %q not correct", test.u, w.Code, w.Body.String())
+		}
+	}
+
+	if s := mux.Routes()[0].String(); s != "{tenant:[a-z]+}.example.com /who" {
+		t.Fatal("host template not introspected:", s)
+	}
+}
+
+func TestMuxTrustForwardedHost(t *testing.T) {
+	mux := NewMux()
+	mux.NewRoute().Host("{tenant:[a-z]+}.example.com").Func(func(ctx *Ctx) error {
+		_, err := ctx.W.Write([]byte(ctx.Args["tenant"]))
+		return err
+	})
+
+	req := httptest.NewRequest("GET", "http://backend:8080/", nil)
+	req.Header.Set("X-Forwarded-Host", "acme.example.com, proxy.internal")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != 404 {
+		t.Fatal("X-Forwarded-Host trusted by default")
+	}
+
+	mux.TrustForwardedHost(true)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != 404 {
+		t.Fatal("X-Forwarded-Host trusted without the proxies")
+	}
+
+	// httptest.NewRequest is sent from 192.0.2.1
+	mux.TrustProxies("192.0.2.1")
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Body.String() != "acme" {
+		t.Fatal("X-Forwarded-Host not trusted:", w.Code, w.Body.String())
+	}
+}
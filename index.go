@@ -0,0 +1,172 @@
+package sexrt
+
+import (
+	"regexp"
+	"sort"
+)
+
+// routeIndex is a segment trie of the route pool keyed on segment count,
+// so that only the routes which may match the request paths are checked
+type routeIndex struct {
+	roots    map[int]*indexNode // segment count => trie root
+	catchAll *indexNode         // trie of routes with catch-all segment, keyed on the prefix
+}
+
+// indexNode is a node of the segment trie
+type indexNode struct {
+	literals map[string]*indexNode // literal segment => child
+	dynamics []*indexEdge          // regexp segments, only checked when needed
+	routes   []int                 // positions in the route pool of routes ending here
+}
+
+// indexEdge is a regexp segment of the segment trie
+type indexEdge struct {
+	reg  *regexp.Regexp
+	node *indexNode
+}
+
+// anyRegexp match any segment
+var anyRegexp = regexp.MustCompile(``)
+
+// newRouteIndex build the segment trie of a route pool
+func newRouteIndex(pool []*routeEntry) *routeIndex {
+	idx := &routeIndex{
+		roots:    make(map[int]*indexNode),
+		catchAll: new(indexNode),
+	}
+
+	for i, entry := range pool {
+		paths := entry.route.paths
+
+		// the catch-all segment is the last one
+		if n := len(paths); n > 0 {
+			if _, ok := paths[n-1].(*catchAll); ok {
+				idx.catchAll.insert(paths[:n-1], i, entry.route.rejectInvalidArgs)
+				continue
+			}
+		}
+
+		// the route with optional segments is indexed under each possible segment count
+		for n := requiredCount(paths); n <= len(paths); n++ {
+			root, ok := idx.roots[n]
+			if !ok {
+				root = new(indexNode)
+				idx.roots[n] = root
+			}
+			root.insert(paths[:n], i, entry.route.rejectInvalidArgs)
+		}
+	}
+
+	return idx
+}
+
+// requiredCount count the path segments before the first optional segment
+func requiredCount(paths []interface{}) int {
+	for i, item := range paths {
+		if nr, ok := item.(*namedRegexp); ok && nr.Optional {
+			return i
+		}
+	}
+	return len(paths)
+}
+
+// insert add the position of a route into the trie, the typed segments of a route which rejects
+// invalid arguments and the last segment template (which may be matched with the extension) match any segment
+func (node *indexNode) insert(paths []interface{}, position int, rejectInvalidArgs bool) {
+	for i, item := range paths {
+		if nr, ok := item.(*namedRegexp); ok && nr.conv != nil && rejectInvalidArgs {
+			item = anyRegexp
+		}
+		if _, ok := item.(*segmentTemplate); ok && i == len(paths)-1 {
+			item = anyRegexp
+		}
+		node = node.child(item)
+	}
+	node.routes = append(node.routes, position)
+}
+
+// child find or create the child node of a path item
+func (node *indexNode) child(item interface{}) *indexNode {
+	var reg *regexp.Regexp
+
+	switch item.(type) {
+	case string:
+		if node.literals == nil {
+			node.literals = make(map[string]*indexNode)
+		}
+		child, ok := node.literals[item.(string)]
+		if !ok {
+			child = new(indexNode)
+			node.literals[item.(string)] = child
+		}
+		return child
+
+	case *regexp.Regexp:
+		reg = item.(*regexp.Regexp)
+
+	case *namedRegexp:
+		reg = item.(*namedRegexp).Regexp
+
+	case *segmentTemplate:
+		reg = item.(*segmentTemplate).Regexp
+
+	default:
+		panic("Unknow type of slice item")
+	}
+
+	// the same regexp share one edge
+	for _, edge := range node.dynamics {
+		if edge.reg.String() == reg.String() {
+			return edge.node
+		}
+	}
+	edge := &indexEdge{reg: reg, node: new(indexNode)}
+	node.dynamics = append(node.dynamics, edge)
+	return edge.node
+}
+
+// lookup return the positions in the route pool of routes which may match the paths, in order
+func (idx *routeIndex) lookup(paths []string) []int {
+	var positions []int
+
+	if root, ok := idx.roots[len(paths)]; ok {
+		root.collect(paths, &positions)
+	}
+	idx.catchAll.collectPrefix(paths, &positions)
+
+	sort.Ints(positions)
+	return positions
+}
+
+// collectPrefix collect the routes of every node on the way, whose prefix match the paths
+func (node *indexNode) collectPrefix(paths []string, positions *[]int) {
+	*positions = append(*positions, node.routes...)
+	if len(paths) == 0 {
+		return
+	}
+
+	if child, ok := node.literals[paths[0]]; ok {
+		child.collectPrefix(paths[1:], positions)
+	}
+	for _, edge := range node.dynamics {
+		if edge.reg.MatchString(paths[0]) {
+			edge.node.collectPrefix(paths[1:], positions)
+		}
+	}
+}
+
+func (node *indexNode) collect(paths []string, positions *[]int) {
+	if len(paths) == 0 {
+		*positions = append(*positions, node.routes...)
+		return
+	}
+
+	if child, ok := node.literals[paths[0]]; ok {
+		child.collect(paths[1:], positions)
+	}
+	for _, edge := range node.dynamics {
+		if edge.reg.MatchString(paths[0]) {
+			edge.node.collect(paths[1:], positions)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package sexrt
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRouteIndexLookup(t *testing.T) {
+	mux := NewMux()
+	mux.NewRoute().Func(testHandler)                                    // 0
+	mux.NewRoute().Path("user", `{name:\w+}`).Func(testHandler)         // 1
+	mux.NewRoute().Path("user", "new").Func(testHandler)                // 2
+	mux.NewRoute().Path(`{\w+}`, "new").Func(testHandler)               // 3
+	mux.NewRoute().Path("user", `{id:^\d+$}`, "edit").Func(testHandler) // 4
+	mux.NewRoute().Path("user", `{^\d+$}`, "edit").Func(testHandler)    // 5
+
+	idx := newRouteIndex(mux.routeHandlerPool)
+
+	tests := []struct {
+		paths     []string
+		positions []int
+	}{
+		{[]string{}, []int{0}},
+		{[]string{"user", "new"}, []int{1, 2, 3}},
+		{[]string{"user", "jmjoy"}, []int{1}},
+		{[]string{"group", "new"}, []int{3}},
+		{[]string{"user", "123", "edit"}, []int{4, 5}},
+		{[]string{"user", "abc", "edit"}, nil},
+		{[]string{"user", "123", "edit", "more"}, nil},
+	}
+	for _, test := range tests {
+		positions := idx.lookup(test.paths)
+		if !reflect.DeepEqual(positions, test.positions) {
+			t.Fatalf("%v: positions %v not equal %v", test.paths, positions, test.positions)
+		}
+	}
+}
+
+func TestRouteIndexLookupWildcard(t *testing.T) {
+	mux := NewMux()
+	mux.NewRoute().Path(`{rest:*}`).Func(testHandler)                             // 0
+	mux.NewRoute().Path("files", `{rest:*}`).Func(testHandler)                    // 1
+	mux.NewRoute().Path("list", `{page?:^\d+$}`).Func(testHandler)                // 2
+	mux.NewRoute().Path("list", `{page?:^\d+$}`, `{size?:\d+}`).Func(testHandler) // 3
+	mux.NewRoute().Path("files", "a").Func(testHandler)                           // 4
+
+	idx := newRouteIndex(mux.routeHandlerPool)
+
+	tests := []struct {
+		paths     []string
+		positions []int
+	}{
+		{[]string{}, []int{0}},
+		{[]string{"files"}, []int{0, 1}},
+		{[]string{"files", "a"}, []int{0, 1, 4}},
+		{[]string{"files", "a", "b"}, []int{0, 1}},
+		{[]string{"list"}, []int{0, 2, 3}},
+		{[]string{"list", "2"}, []int{0, 2, 3}},
+		{[]string{"list", "2", "10"}, []int{0, 3}},
+		{[]string{"list", "x"}, []int{0}},
+	}
+	for _, test := range tests {
+		positions := idx.lookup(test.paths)
+		if !reflect.DeepEqual(positions, test.positions) {
+			t.Fatalf("%v: positions %v not equal %v", test.paths, positions, test.positions)
+		}
+	}
+}
+
+func BenchmarkMuxMatch(b *testing.B) {
+	for _, n := range []int{10, 1000, 10000} {
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			mux := NewMux()
+			for i := 0; i < n; i++ {
+				mux.NewRoute().Path("api", fmt.Sprintf("res%d", i), `{id:^\d+$}`).Func(testHandler)
+			}
+
+			r := httptest.NewRequest("GET", fmt.Sprintf("/api/res%d/123", n-1), nil)
+			// build the index before timing
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				mux.ServeHTTP(httptest.NewRecorder(), r)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package sexrt
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RouteInfo is the description of a registered route
+type RouteInfo struct {
+	Name     string
+	Priority int
+	Path     string              // path template (e.g. "/user/{id:\d+}")
+	Methods  []string            // method patterns
+	Hosts    []string            // host patterns
+	Schemes  []string            // scheme patterns
+	Exts     []string            // extension patterns
+	Querys   map[string][]string // query patterns
+	Headers  map[string][]string // header patterns
+	Matchers []string            // labels of user defined predicates
+	Produces []string            // response media types
+	Consumes []string            // request media types
+}
+
+// Routes return the descriptions of all registered routes in matching order
+func (mux *Mux) Routes() []RouteInfo {
+	var infos []RouteInfo
+	mux.Walk(func(info RouteInfo) error {
+		infos = append(infos, info)
+		return nil
+	})
+	return infos
+}
+
+// Walk call fn with the description of each registered route in matching order,
+// and stop at the first error returned by fn
+func (mux *Mux) Walk(fn func(RouteInfo) error) error {
+	// walk a snapshot, so fn can register routes
+	mux.mu.RLock()
+	pool := append([]*routeEntry(nil), mux.routeHandlerPool...)
+	mux.mu.RUnlock()
+
+	for _, entry := range pool {
+		if err := fn(entry.route.Info()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Info return the description of a route
+func (rt *Route) Info() RouteInfo {
+	return RouteInfo{
+		Name:     rt.name,
+		Priority: rt.priority,
+		Path:     rt.pathTemplate(),
+		Methods:  patternSlice(rt.methods),
+		Hosts:    patternSlice(rt.hosts),
+		Schemes:  patternSlice(rt.schemes),
+		Exts:     patternSlice(rt.exts),
+		Querys:   patternMap(rt.querys),
+		Headers:  patternMap(rt.headers),
+		Matchers: matcherLabels(rt.matchers),
+		Produces: append([]string(nil), rt.produces...),
+		Consumes: append([]string(nil), rt.consumes...),
+	}
+}
+
+// pathTemplate return the pattern syntax of route paths (e.g. "/user/{id:\d+}")
+func (rt *Route) pathTemplate() string {
+	return "/" + strings.Join(patternSlice(rt.paths), "/")
+}
+
+// String return the pattern syntax of a route
+func (rt *Route) String() string {
+	return rt.Info().String()
+}
+
+// String return the pattern syntax of a route (e.g. "GET,POST https api.example.com /user/{id:\d+}.{html|json}")
+func (info RouteInfo) String() string {
+	var parts []string
+
+	if len(info.Methods) > 0 {
+		parts = append(parts, strings.Join(info.Methods, ","))
+	}
+	if len(info.Schemes) > 0 {
+		parts = append(parts, strings.Join(info.Schemes, ","))
+	}
+	if len(info.Hosts) > 0 {
+		parts = append(parts, strings.Join(info.Hosts, ","))
+	}
+
+	u := info.Path
+	switch len(info.Exts) {
+	case 0:
+	case 1:
+		if info.Exts[0] != "" {
+			u += "." + info.Exts[0]
+		}
+	default:
+		u += ".{" + strings.Join(info.Exts, "|") + "}"
+	}
+	if len(info.Querys) > 0 {
+		var querys []string
+		for _, k := range sortedKeys(info.Querys) {
+			for _, v := range info.Querys[k] {
+				querys = append(querys, k+"="+v)
+			}
+		}
+		u += "?" + strings.Join(querys, "&")
+	}
+	parts = append(parts, u)
+
+	for _, k := range sortedKeys(info.Headers) {
+		for _, v := range info.Headers[k] {
+			parts = append(parts, k+":"+v)
+		}
+	}
+
+	if len(info.Consumes) > 0 {
+		parts = append(parts, "Content-Type:"+strings.Join(info.Consumes, ","))
+	}
+	if len(info.Produces) > 0 {
+		parts = append(parts, "Produces:"+strings.Join(info.Produces, ","))
+	}
+
+	for _, label := range info.Matchers {
+		parts = append(parts, "MatchFunc("+label+")")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// patternSingle return the pattern syntax of a route item
+func patternSingle(item interface{}) string {
+	switch item.(type) {
+	case string:
+		return item.(string)
+
+	case *regexp.Regexp:
+		return "{" + item.(*regexp.Regexp).String() + "}"
+
+	case *namedRegexp:
+		nr := item.(*namedRegexp)
+
+		name := nr.Name
+		if nr.Optional {
+			name += "?"
+		}
+		if nr.conv != nil {
+			return "{" + name + ":" + nr.conv.Name + "}"
+		}
+		return "{" + name + ":" + nr.String() + "}"
+
+	case *catchAll:
+		if ca := item.(*catchAll); ca.Name != "" {
+			return "{" + ca.Name + ":*}"
+		}
+		return "{*}"
+
+	case *hostTemplate:
+		return item.(*hostTemplate).Pattern
+
+	case *segmentTemplate:
+		return item.(*segmentTemplate).Pattern
+
+	default:
+		panic("Unknow type of slice item")
+	}
+}
+
+func patternSlice(slice []interface{}) []string {
+	if len(slice) == 0 {
+		return nil
+	}
+
+	patterns := make([]string, 0, len(slice))
+	for _, item := range slice {
+		patterns = append(patterns, patternSingle(item))
+	}
+	return patterns
+}
+
+func patternMap(m map[string][]interface{}) map[string][]string {
+	if len(m) == 0 {
+		return nil
+	}
+
+	patterns := make(map[string][]string, len(m))
+	for k, slice := range m {
+		patterns[k] = patternSlice(slice)
+	}
+	return patterns
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,53 @@
+package sexrt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMuxRoutes(t *testing.T) {
+	mux := NewMux()
+	mux.NewRoute().Name("user").Method("GET", "POST").Host("api.example.com").
+		Path("user", `{id:\d+}`).Ext("html", "json").Func(testHandler)
+	mux.NewRoute().Path("files", `{rest:*}`).Ext("").Func(testHandler)
+	mux.NewRoute().Path("list", `{^\d+$}`, `{page?:int}`).
+		Query("lang", "en", "q", `{q:\w+}`).Header("Accept", `{html}`).
+		Priority(1).Func(testHandler)
+
+	routes := mux.Routes()
+	if len(routes) != 3 {
+		t.Fatal("len of routes isn't 3")
+	}
+
+	expect := RouteInfo{
+		Name:    "user",
+		Path:    `/user/{id:\d+}`,
+		Methods: []string{"GET", "POST"},
+		Hosts:   []string{"api.example.com"},
+		Exts:    []string{"html", "json"},
+	}
+	if !reflect.DeepEqual(routes[1], expect) {
+		t.Fatalf("%#v not equal %#v", routes[1], expect)
+	}
+
+	expectStrings := []string{
+		`/list/{^\d+$}/{page?:int}?lang=en&q={q:\w+} Accept:{html}`,
+		`GET,POST api.example.com /user/{id:\d+}.{html|json}`,
+		`/files/{rest:*}`,
+	}
+	for i, route := range routes {
+		if route.String() != expectStrings[i] {
+			t.Fatalf("%q not equal %q", route.String(), expectStrings[i])
+		}
+	}
+
+	count := 0
+	err := mux.Walk(func(info RouteInfo) error {
+		count++
+		return errHehe
+	})
+	if count != 1 || !errors.Is(err, errHehe) {
+		t.Fatal("walk not stop at the first error")
+	}
+}
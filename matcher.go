@@ -0,0 +1,74 @@
+package sexrt
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// customMatcher is a user defined predicate of a route
+type customMatcher struct {
+	fn    func(r *http.Request, args map[string]string) bool
+	Label string // the name of the function, used for introspection
+}
+
+// MatchFunc add a user defined predicate to a building route (e.g. feature flags, client IP ranges),
+// the args are the arguments captured so far, and the predicate can add some arguments into it,
+// they are discarded unless the route fully match
+func (rt *Route) MatchFunc(fn func(r *http.Request, args map[string]string) bool) *Route {
+	return rt.MatchFuncNamed(funcLabel(fn), fn)
+}
+
+// MatchFuncNamed is like MatchFunc but with a label for introspection, useful for the closures
+func (rt *Route) MatchFuncNamed(label string, fn func(r *http.Request, args map[string]string) bool) *Route {
+	rt.matchers = append(rt.matchers, &customMatcher{fn: fn, Label: label})
+	return rt
+}
+
+// funcLabel return the short name of a function (e.g. "main.isBeta", "main.main.func1")
+func funcLabel(fn interface{}) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "func"
+	}
+
+	name := f.Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// cloneMatchers clone the user defined predicates of a route
+func cloneMatchers(matchers []*customMatcher) []*customMatcher {
+	if matchers == nil {
+		return nil
+	}
+
+	newMatchers := make([]*customMatcher, 0, len(matchers))
+	for _, cm := range matchers {
+		newCm := *cm
+		newMatchers = append(newMatchers, &newCm)
+	}
+	return newMatchers
+}
+
+// matcherLabels return the labels of the user defined predicates of a route
+func matcherLabels(matchers []*customMatcher) []string {
+	var labels []string
+	for _, cm := range matchers {
+		labels = append(labels, cm.Label)
+	}
+	return labels
+}
+
+// isMatchersMatch check all the user defined predicates of a route
+func isMatchersMatch(matchers []*customMatcher, r *http.Request, st *matchState) bool {
+	for _, cm := range matchers {
+		if !cm.fn(r, st.args) {
+			return false
+		}
+	}
+	return true
+}
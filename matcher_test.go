@@ -0,0 +1,102 @@
+package sexrt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func isBeta(r *http.Request, args map[string]string) bool {
+	if r.Header.Get("X-Beta") != "on" {
+		return false
+	}
+	args["channel"] = "beta"
+	return true
+}
+
+func TestRouteMatchFunc(t *testing.T) {
+	mux := NewMux()
+	mux.MostSpecificWins(true)
+
+	var calls int
+	rt := mux.NewRoute().Path("home", `{name:\w+}`)
+	rt.Func(func(ctx *Ctx) error {
+		_, err := ctx.W.Write([]byte("stable:" + ctx.Args["channel"] + ctx.Args["name"]))
+		return err
+	})
+	// registered later, but more specific
+	rt.MatchFunc(isBeta).MatchFuncNamed("known", func(r *http.Request, args map[string]string) bool {
+		calls++
+		return args["name"] != "nobody"
+	}).Func(func(ctx *Ctx) error {
+		_, err := ctx.W.Write([]byte(ctx.Args["channel"] + ":" + ctx.Args["name"]))
+		return err
+	})
+
+	tests := []struct {
+		u      string
+		header map[string]string
+		body   string
+	}{
+		{"/home/jmjoy", nil, "stable:jmjoy"},
+		{"/home/jmjoy", map[string]string{"X-Beta": "on"}, "beta:jmjoy"},
+		// the argument added by a mismatched route is discarded
+		{"/home/nobody", map[string]string{"X-Beta": "on"}, "stable:nobody"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.u, nil)
+		for k, v := range test.header {
+			req.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Body.String() != test.body {
+			t.Fatalf("%s %v: body %q not equal %q", test.u, test.header, w.Body.String(), test.body)
+		}
+	}
+	if calls != 2 {
+		t.Fatal("predicate not called only after the former matchers:", calls)
+	}
+
+	// the builder is cloned
+	if len(rt.matchers) != 2 || len(mux.Routes()[0].Matchers) != 2 || len(mux.Routes()[1].Matchers) != 0 {
+		t.Fatal("matchers not cloned")
+	}
+
+	s := mux.Routes()[0].String()
+	if !strings.HasPrefix(s, "/home/{name:\\w+} MatchFunc(") || !strings.HasSuffix(s, ".isBeta) MatchFunc(known)") {
+		t.Fatal("matchers not introspected:", s)
+	}
+}
+
+func TestRouteMatchFuncCallback(t *testing.T) {
+	mux := NewMux()
+	mux.NewRoute().Name("home").Path("home").Func(testHandler)
+
+	done := make(chan struct{})
+	mux.NewRoute().Path("link").MatchFunc(func(r *http.Request, args map[string]string) bool {
+		// a writer waiting for the lock while the predicate calls back into the Mux
+		go func() {
+			mux.NewRoute().Path("other").Func(testHandler)
+			close(done)
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		u, err := mux.URL("home", nil)
+		args["home"] = u
+		return err == nil
+	}).Func(func(ctx *Ctx) error {
+		_, err := ctx.W.Write([]byte(ctx.Args["home"]))
+		return err
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/link", nil))
+	if w.Body.String() != "/home" {
+		t.Fatal("/link: body not correct:", w.Body.String())
+	}
+	<-done
+}
@@ -0,0 +1,29 @@
+package sexrt
+
+// Middleware wrap a handler, it runs after the route matching,
+// so ctx.Args and ctx.Route of the matched route are available
+type Middleware func(next func(*Ctx) error) func(*Ctx) error
+
+// Use add some middlewares to this Mux, they wrap all the routes
+// and the not found handler, the first one is the outermost
+func (mux *Mux) Use(mws ...Middleware) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.middlewares = append(mux.middlewares, mws...)
+}
+
+// Use add some middlewares to a building route, they wrap the routes built from it,
+// inside the middlewares of the Mux
+func (rt *Route) Use(mws ...Middleware) *Route {
+	rt.middlewares = append(rt.middlewares, mws...)
+	return rt
+}
+
+// wrapMiddlewares wrap a handler by middlewares, the first one is the outermost
+func wrapMiddlewares(fn routeHandler, mws []Middleware) routeHandler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		fn = mws[i](fn)
+	}
+	return fn
+}
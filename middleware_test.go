@@ -0,0 +1,59 @@
+package sexrt
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testMiddleware(name string) Middleware {
+	return func(next func(*Ctx) error) func(*Ctx) error {
+		return func(ctx *Ctx) error {
+			io.WriteString(ctx.W, name+"(")
+			err := next(ctx)
+			io.WriteString(ctx.W, ")")
+			return err
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.Use(testMiddleware("mux0"), testMiddleware("mux1"))
+	mux.HandleNotFound(testWriteHandler("notfound"))
+
+	rt := mux.NewRoute().Path("user").Use(testMiddleware("route"))
+	rt.Path(`{name:\w+}`).Use(func(next func(*Ctx) error) func(*Ctx) error {
+		return func(ctx *Ctx) error {
+			if ctx.Route == nil {
+				t.Error("matched route not available")
+			}
+			io.WriteString(ctx.W, "name:"+ctx.Args["name"]+"(")
+			err := next(ctx)
+			io.WriteString(ctx.W, ")")
+			return err
+		}
+	}).Func(testWriteHandler("user"))
+	mux.NewRoute().Path("index").Func(testWriteHandler("index"))
+
+	tests := []struct {
+		u    string
+		body string
+	}{
+		{"/user/jmjoy", "mux0(mux1(route(name:jmjoy(user))))"},
+		{"/index", "mux0(mux1(index))"},
+		{"/none", "mux0(mux1(notfound))"},
+	}
+	for _, test := range tests {
+		testHTTPResponse("GET", srv.URL+test.u, "", func(body string, resp *http.Response) {
+			if body != test.body {
+				t.Fatalf("%s: body %q not equal %q", test.u, body, test.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package sexrt
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type mountFallbackContextKey struct{}
+
+// Mount register a http.Handler under the paths of the building route as a prefix,
+// the prefix is stripped from the request path before delegating, and the captures of
+// the prefix can be read by ArgsFromRequest.
+// If the handler is a *Mux, its routes see the captures of this route merged with their own,
+// and its not found handler fall back to this Mux if it is not set.
+func (rt *Route) Mount(h http.Handler) {
+	prefixLen := len(rt.paths)
+	mux := rt.mux
+
+	rt.clone().Path(`{*}`).Func(func(ctx *Ctx) error {
+		fallback := func() error {
+			ctx.Route = nil
+			return mux.findNotFound(ctx)(ctx)
+		}
+		r := ctx.R.WithContext(context.WithValue(ctx.R.Context(), mountFallbackContextKey{}, fallback))
+		h.ServeHTTP(ctx.W, stripPathPrefix(r, prefixLen))
+		return nil
+	})
+}
+
+// muxNotFound return the not found handler of this Mux,
+// fall back to the outer Mux if it is not set and this Mux is mounted
+func (mux *Mux) muxNotFound(ctx *Ctx) routeHandler {
+	mux.mu.RLock()
+	notFoundHandler := mux.notFoundHandler
+	mux.mu.RUnlock()
+
+	if notFoundHandler != nil {
+		return notFoundHandler
+	}
+
+	if fallback, ok := ctx.R.Context().Value(mountFallbackContextKey{}).(func() error); ok {
+		return func(*Ctx) error {
+			return fallback()
+		}
+	}
+
+	// default Not Found handler
+	return func(ctx *Ctx) error {
+		http.NotFound(ctx.W, ctx.R)
+		return nil
+	}
+}
+
+// stripPathPrefix return a shallow copy of request with n segments stripped from the url path
+func stripPathPrefix(r *http.Request, n int) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+
+	r2.URL.Path = stripSegments(r.URL.Path, n)
+	if r.URL.RawPath != "" {
+		r2.URL.RawPath = stripSegments(r.URL.RawPath, n)
+		// the escaped slashes make the segments different, let url recompute it
+		if unescaped, err := url.PathUnescape(r2.URL.RawPath); err != nil || unescaped != r2.URL.Path {
+			r2.URL.RawPath = ""
+		}
+	}
+
+	return r2
+}
+
+// stripSegments strip n segments from the beginning of a path, the result starts with "/"
+func stripSegments(p string, n int) string {
+	for i := 0; i < n; i++ {
+		p = strings.TrimLeft(p, "/")
+		index := strings.Index(p, "/")
+		if index < 0 {
+			return "/"
+		}
+		p = p[index:]
+	}
+
+	if p == "" {
+		return "/"
+	}
+	return p
+}
@@ -0,0 +1,78 @@
+package sexrt
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteMount(t *testing.T) {
+	outer := NewMux()
+
+	srv := httptest.NewServer(outer)
+	defer srv.Close()
+
+	outer.HandleNotFound(testWriteHandler("outer notfound"))
+
+	// foreign handler
+	outer.NewRoute().Path("static", `{version:^v\d+$}`).Mount(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", ArgsFromRequest(r)["version"], r.URL.Path, r.URL.EscapedPath())
+	}))
+
+	// inner mux
+	inner := NewMux()
+	inner.NewRoute().Path("users", `{id:int}`).Func(func(ctx *Ctx) error {
+		_, err := fmt.Fprintf(ctx.W, "%s:%d", ctx.Args["version"], ctx.Int("id"))
+		return err
+	})
+	inner.NewRoute().Func(testWriteHandler("inner index"))
+	outer.NewRoute().Path("api", `{version:^v\d+$}`).Mount(inner)
+
+	tests := []struct {
+		u    string
+		body string
+	}{
+		{"/static/v1/css/main.css", "v1 /css/main.css /css/main.css"},
+		{"/static/v1/a%20b/c%2Fd", "v1 /a b/c/d /a%20b/c%2Fd"},
+		{"/static/v1", "v1 / /"},
+		{"/api/v2/users/42", "v2:42"},
+		{"/api/v2", "inner index"},
+		{"/api/v2/none", "outer notfound"},
+		{"/none", "outer notfound"},
+	}
+	for _, test := range tests {
+		testHTTPResponse("GET", srv.URL+test.u, "", func(body string, resp *http.Response) {
+			if body != test.body {
+				t.Fatalf("%s: body %q not equal %q", test.u, body, test.body)
+			}
+		})
+	}
+
+	inner.HandleNotFound(testWriteHandler("inner notfound"))
+	testHTTPResponse("GET", srv.URL+"/api/v2/none", "", func(body string, resp *http.Response) {
+		if body != "inner notfound" {
+			t.Fatal("/api/v2/none: body not correct:", body)
+		}
+	})
+}
+
+func TestStripSegments(t *testing.T) {
+	tests := []struct {
+		p      string
+		n      int
+		result string
+	}{
+		{"/a/b/c", 0, "/a/b/c"},
+		{"/a/b/c", 1, "/b/c"},
+		{"/a/b/c/", 2, "/c/"},
+		{"/a/b", 2, "/"},
+		{"/a/b/", 2, "/"},
+		{"/", 1, "/"},
+	}
+	for _, test := range tests {
+		if result := stripSegments(test.p, test.n); result != test.result {
+			t.Fatalf("%q %d: %q not equal %q", test.p, test.n, result, test.result)
+		}
+	}
+}
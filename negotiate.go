@@ -0,0 +1,205 @@
+package sexrt
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// mediaRange is a media range of the "Accept" header (e.g. "text/*;q=0.8")
+type mediaRange struct {
+	typ, subtype string
+	params       int     // the number of parameters except "q", more specific if more
+	q            float64 // the quality value
+}
+
+// Produces add some response media types to a building route (e.g. "application/json", "text/html"),
+// the route match if the client accept one of them by the "Accept" header, and the routes with
+// the same priority are negotiated by the client preference. The url extension (e.g. "/user/1.json")
+// overrides the "Accept" header. The negotiated media type is got by Ctx.MediaType.
+func (rt *Route) Produces(mediaTypes ...string) *Route {
+	for i, mediaType := range mediaTypes {
+		if err := validMediaType(mediaType); err != nil {
+			rt.addError("Produces", mediaType, i, err)
+			continue
+		}
+		rt.produces = append(rt.produces, strings.ToLower(mediaType))
+	}
+	return rt
+}
+
+// Consumes add some request media types to a building route (e.g. "application/json", "text/*"),
+// the route match if the "Content-Type" header is one of them
+func (rt *Route) Consumes(mediaTypes ...string) *Route {
+	for i, mediaType := range mediaTypes {
+		if err := validMediaType(mediaType); err != nil {
+			rt.addError("Consumes", mediaType, i, err)
+			continue
+		}
+		rt.consumes = append(rt.consumes, strings.ToLower(mediaType))
+	}
+	return rt
+}
+
+// validMediaType check a media type has the "type/subtype" form
+func validMediaType(s string) error {
+	mediaType, _, err := mime.ParseMediaType(s)
+	if err != nil {
+		return err
+	}
+	if i := strings.IndexByte(mediaType, '/'); i <= 0 || i == len(mediaType)-1 {
+		return errors.New(`media type isn't "type/subtype"`)
+	}
+	return nil
+}
+
+// MediaType return the response media type negotiated by the Produces of the matched route,
+// or empty if the route doesn't produce any media type
+func (ctx *Ctx) MediaType() string {
+	return ctx.mediaType
+}
+
+// parseAccept parse the "Accept" header into media ranges, the invalid ones are ignored
+func parseAccept(accept string) []mediaRange {
+	ranges := make([]mediaRange, 0, strings.Count(accept, ",")+1)
+
+	for _, s := range strings.Split(accept, ",") {
+		parts := strings.Split(s, ";")
+
+		mr := mediaRange{q: 1}
+		full := strings.ToLower(strings.TrimSpace(parts[0]))
+		if full == "" {
+			continue
+		}
+		if full == "*" {
+			// some clients send "*" for "*/*"
+			full = "*/*"
+		}
+		i := strings.IndexByte(full, '/')
+		if i <= 0 || i == len(full)-1 {
+			continue
+		}
+		mr.typ, mr.subtype = full[:i], full[i+1:]
+
+		valid := true
+		for _, param := range parts[1:] {
+			key, value := param, ""
+			if i := strings.IndexByte(param, '='); i >= 0 {
+				key, value = param[:i], param[i+1:]
+			}
+			if !strings.EqualFold(strings.TrimSpace(key), "q") {
+				mr.params++
+				continue
+			}
+
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+				break
+			}
+			mr.q = q
+		}
+
+		if valid {
+			ranges = append(ranges, mr)
+		}
+	}
+
+	return ranges
+}
+
+// acceptRanges return the parsed "Accept" header of the request, it is parsed once
+func (ctx *Ctx) acceptRanges() []mediaRange {
+	if ctx.accept == nil {
+		ctx.accept = parseAccept(ctx.R.Header.Get("Accept"))
+	}
+	return ctx.accept
+}
+
+// quality return the quality value of a media type by the most specific matched range
+func quality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype := mediaType, ""
+	if i := strings.IndexByte(mediaType, '/'); i >= 0 {
+		typ, subtype = mediaType[:i], mediaType[i+1:]
+	}
+
+	q, precedence := 0.0, -1
+	for _, mr := range ranges {
+		p := 0
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			p = 2
+		case mr.typ == typ && mr.subtype == "*":
+			p = 1
+		case mr.typ == "*" && mr.subtype == "*":
+		default:
+			continue
+		}
+
+		// the more parameters the more specific
+		p = p*100 + mr.params
+		if p > precedence {
+			q, precedence = mr.q, p
+		}
+	}
+	return q
+}
+
+// negotiate choose the produced media type which the client prefer,
+// the url extension overrides the "Accept" header, and return 0 quality if none is acceptable
+func negotiate(produces []string, ctx *Ctx) (mediaType string, q float64) {
+	var ranges []mediaRange
+	if typ := extMediaType(ctx.ext); typ != "" {
+		ranges = parseAccept(typ)
+	} else if ctx.R.Header.Get("Accept") == "" {
+		// no "Accept" header means accept everything
+		return produces[0], 1
+	} else {
+		ranges = ctx.acceptRanges()
+	}
+
+	for _, produce := range produces {
+		if pq := quality(ranges, produce); pq > q {
+			mediaType, q = produce, pq
+		}
+	}
+	return
+}
+
+// extMediaType return the media type of a url extension without parameters (e.g. "json" => "application/json")
+func extMediaType(ext string) string {
+	if ext == "" {
+		return ""
+	}
+	typ, _, err := mime.ParseMediaType(mime.TypeByExtension("." + ext))
+	if err != nil || !strings.Contains(typ, "/") {
+		return ""
+	}
+	return typ
+}
+
+// isConsumesMatch check the "Content-Type" header is one of the consumed media types
+func isConsumesMatch(consumes []string, r *http.Request) bool {
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	ranges := []mediaRange{}
+	for _, consume := range consumes {
+		ranges = append(ranges, parseAccept(consume)...)
+	}
+	return quality(ranges, contentType) > 0
+}
+
+// notAcceptableHandler respond "406 Not Acceptable" by the error handler
+func notAcceptableHandler(ctx *Ctx) error {
+	return Error(http.StatusNotAcceptable, "")
+}
+
+// unsupportedMediaTypeHandler respond "415 Unsupported Media Type" by the error handler
+func unsupportedMediaTypeHandler(ctx *Ctx) error {
+	return Error(http.StatusUnsupportedMediaType, "")
+}
@@ -0,0 +1,114 @@
+package sexrt
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteProduces(t *testing.T) {
+	mux := NewMux()
+
+	writeMediaType := func(ctx *Ctx) error {
+		_, err := ctx.W.Write([]byte(ctx.MediaType()))
+		return err
+	}
+	rt := mux.NewRoute().Path("user", `{id:\d+}`)
+	rt.Produces("application/json").Func(writeMediaType)
+	rt.Produces("text/html").Func(writeMediaType)
+	mux.NewRoute().Path("page").Produces("text/html", "text/plain").Func(writeMediaType)
+
+	tests := []struct {
+		u      string
+		accept string
+		status int
+		body   string
+	}{
+		{"/user/1", "", 200, "application/json"},
+		{"/user/1", "*/*", 200, "application/json"},
+		{"/user/1", "text/html,application/json;q=0.9", 200, "text/html"},
+		{"/user/1", "text/*, application/json;q=0.5", 200, "text/html"},
+		{"/user/1", "Application/JSON", 200, "application/json"},
+		{"/user/1", "image/png", 406, ""},
+		{"/user/1", "*/*;q=0.1, application/json;q=0", 200, "text/html"},
+		// the url extension overrides the "Accept" header
+		{"/user/1.json", "text/html", 200, "application/json"},
+		{"/user/1.html", "application/json", 200, "text/html"},
+		{"/user/1.png", "*/*", 406, ""},
+		{"/page", "text/plain, text/html;q=0.8", 200, "text/plain"},
+		{"/page", "text/*;q=0.5, text/html;level=1", 200, "text/html"},
+		{"/page", "text/*, text/html;q=0", 200, "text/plain"},
+		{"/page", "application/json", 406, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.u, nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != test.status || (test.status == 200 && w.Body.String() != test.body) {
+			t.Fatalf("%s %q: response %d %q not correct", test.u, test.accept, w.Code, w.Body.String())
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Fatalf("%s %q: Vary header not set", test.u, test.accept)
+		}
+	}
+}
+
+func TestRouteConsumes(t *testing.T) {
+	mux := NewMux()
+	mux.NewRoute().Path("user").Post().Consumes("application/json", "text/*").Func(testWriteHandler("created"))
+
+	tests := []struct {
+		method      string
+		contentType string
+		status      int
+	}{
+		{"POST", "application/json", 200},
+		{"POST", "Application/JSON; charset=utf-8", 200},
+		{"POST", "text/plain", 200},
+		{"POST", "application/xml", 415},
+		{"POST", "", 415},
+		{"PUT", "application/xml", 405},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/user", strings.NewReader("{}"))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Fatalf("%s %q: status %d not equal %d", test.method, test.contentType, w.Code, test.status)
+		}
+	}
+
+	if s := mux.Routes()[0].String(); s != "POST /user Content-Type:application/json,text/*" {
+		t.Fatal("media types not introspected:", s)
+	}
+
+	// the introspected media types are copies
+	mux.Routes()[0].Consumes[0] = "text/html"
+	if consumes := mux.Routes()[0].Consumes; consumes[0] != "application/json" {
+		t.Fatal("route media types modified by the introspection:", consumes)
+	}
+}
+
+func TestRouteMediaTypeErr(t *testing.T) {
+	rt := new(Route).Produces("application/json", "json").Consumes("text/", "text/*")
+
+	var errs RouteErrors
+	if !errors.As(rt.Err(), &errs) || len(errs) != 2 {
+		t.Fatal("errors not recorded:", rt.Err())
+	}
+	if errs[0].Field != "Produces" || errs[0].Position != 1 || errs[1].Field != "Consumes" || errs[1].Position != 0 {
+		t.Fatal("errors not correct:", errs)
+	}
+	if len(rt.produces) != 1 || len(rt.consumes) != 1 {
+		t.Fatal("invalid media types added:", rt.produces, rt.consumes)
+	}
+}
@@ -0,0 +1,103 @@
+package sexrt
+
+import (
+	"sort"
+)
+
+// routeEntry is a registered route and its handler
+type routeEntry struct {
+	route *Route
+	fn    routeHandler
+	seq   int // registration order
+}
+
+// MostSpecificWins will sort the routes with the same priority by specificity,
+// literal path segments beat regexps, and routes with more constraints beat fewer.
+// Routes with the same specificity are still matched in registration order.
+func (mux *Mux) MostSpecificWins(on bool) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.mostSpecific = on
+	mux.index = nil
+
+	pool := append([]*routeEntry(nil), mux.routeHandlerPool...)
+	sort.SliceStable(pool, func(i, j int) bool {
+		return mux.routeLess(pool[i], pool[j])
+	})
+	mux.routeHandlerPool = pool
+}
+
+// addRoute insert a route into the pool, keep the pool sorted in matching order
+func (mux *Mux) addRoute(rt *Route, fn routeHandler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	if rt.name != "" && mux.namedRoutes[rt.name] != nil {
+		rt.addError("Name", rt.name, 0, errDuplicateName)
+	}
+	if mux.rejectInvalid(rt) {
+		return
+	}
+
+	mux.routeSeq++
+	entry := &routeEntry{route: rt, fn: fn, seq: mux.routeSeq}
+
+	pool := mux.routeHandlerPool
+	i := sort.Search(len(pool), func(i int) bool {
+		return mux.routeLess(entry, pool[i])
+	})
+	// the pool may be matched outside the lock, so insert into a copy
+	newPool := make([]*routeEntry, 0, len(pool)+1)
+	newPool = append(append(append(newPool, pool[:i]...), entry), pool[i:]...)
+	mux.routeHandlerPool = newPool
+	mux.index = nil
+
+	if rt.name != "" {
+		mux.namedRoutes[rt.name] = rt
+	}
+}
+
+// routeLess report whether the route a should be matched before the route b
+func (mux *Mux) routeLess(a, b *routeEntry) bool {
+	if a.route.priority != b.route.priority {
+		return a.route.priority > b.route.priority
+	}
+
+	if mux.mostSpecific {
+		aLiterals, aConstraints := a.route.specificity()
+		bLiterals, bConstraints := b.route.specificity()
+		if aLiterals != bLiterals {
+			return aLiterals > bLiterals
+		}
+		if aConstraints != bConstraints {
+			return aConstraints > bConstraints
+		}
+	}
+
+	return a.seq < b.seq
+}
+
+// specificity count the literal path segments and the other constraints of a route
+func (rt *Route) specificity() (literals, constraints int) {
+	for _, item := range rt.paths {
+		if _, ok := item.(string); ok {
+			literals++
+		}
+	}
+
+	for _, slice := range [][]interface{}{rt.methods, rt.hosts, rt.schemes, rt.exts} {
+		if len(slice) > 0 {
+			constraints++
+		}
+	}
+	constraints += len(rt.querys) + len(rt.headers) + len(rt.matchers)
+	if len(rt.produces) > 0 {
+		constraints++
+	}
+	if len(rt.consumes) > 0 {
+		constraints++
+	}
+
+	return
+}
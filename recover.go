@@ -0,0 +1,78 @@
+package sexrt
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// Logger is used by Mux to log the errors, *log.Logger is a Logger
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// SetLogger will set user defined logger to this Mux, the default one writes to os.Stderr
+func (mux *Mux) SetLogger(logger Logger) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.logger = logger
+}
+
+// PanicError is the error passed to the error handler when a handler panics
+type PanicError struct {
+	Value interface{} // the value passed to panic
+	Stack []byte      // the stack trace of the panic
+	Route *Route      // the matched route, nil if not found
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("sexrt: panic: %v", e.Value)
+}
+
+// Unwrap return the value passed to panic if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// serve call the handler, the returned error and the recovered panic are passed to the error handler
+func (mux *Mux) serve(ctx *Ctx, fn routeHandler) {
+	mux.mu.RLock()
+	errorHandler := mux.errorHandler
+	mux.mu.RUnlock()
+
+	defer func() {
+		if v := recover(); v != nil {
+			// keep the behaviour of net/http to abort the response
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			errorHandler(ctx, &PanicError{Value: v, Stack: debug.Stack(), Route: ctx.Route})
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		errorHandler(ctx, err)
+	}
+}
+
+// logError log the error with the request, and the matched route and the stack trace of a panic
+func (mux *Mux) logError(ctx *Ctx, err error) {
+	mux.mu.RLock()
+	logger := mux.logger
+	mux.mu.RUnlock()
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		logger.Printf("%s %s: %v", ctx.R.Method, ctx.R.URL, err)
+		return
+	}
+
+	route := "<not found>"
+	if panicErr.Route != nil {
+		route = fmt.Sprintf("%q", panicErr.Route.name)
+	}
+	logger.Printf("%s %s: route %s: %v\n%s", ctx.R.Method, ctx.R.URL, route, err, panicErr.Stack)
+}
@@ -0,0 +1,65 @@
+package sexrt
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMuxRecover(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	buf := new(bytes.Buffer)
+	mux.SetLogger(log.New(buf, "", 0))
+
+	mux.NewRoute().Name("boom").Path("boom").Func(func(ctx *Ctx) error {
+		panic("boom")
+	})
+
+	testHTTPResponse("GET", srv.URL+"/boom", "", func(body string, resp *http.Response) {
+		if resp.StatusCode != 500 {
+			t.Fatal("/boom: panic not recovered to 500")
+		}
+	})
+	if logged := buf.String(); !strings.Contains(logged, `route "boom": sexrt: panic: boom`) ||
+		!strings.Contains(logged, "recover_test.go") {
+		t.Fatal("panic not logged with route and stack trace:", logged)
+	}
+
+	var panicErr *PanicError
+	mux.HandleError(func(ctx *Ctx, err error) {
+		if !errors.As(err, &panicErr) {
+			t.Error("not a PanicError:", err)
+		}
+		ctx.W.WriteHeader(500)
+	})
+
+	testHTTPResponse("GET", srv.URL+"/boom", "", func(body string, resp *http.Response) {
+		if panicErr == nil || panicErr.Value != "boom" || panicErr.Route == nil ||
+			panicErr.Route.name != "boom" || len(panicErr.Stack) == 0 {
+			t.Fatal("/boom: PanicError not correct")
+		}
+	})
+
+	// panic inside the not found handler
+	panicErr = nil
+	mux.HandleNotFound(func(ctx *Ctx) error {
+		panic(errHehe)
+	})
+
+	testHTTPResponse("GET", srv.URL+"/none", "", func(body string, resp *http.Response) {
+		if resp.StatusCode != 500 {
+			t.Fatal("/none: panic not recovered")
+		}
+		if panicErr == nil || panicErr.Route != nil || !errors.Is(panicErr, errHehe) {
+			t.Fatal("/none: PanicError not correct")
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package sexrt
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrRouteNotFound is returned by Mux.URL when there is no route with the given name
+var ErrRouteNotFound = errors.New("sexrt: route not found")
+
+// URL build the url of a named route, the named regexps will be substituted by args.
+// It returns an error when an argument is missing or doesn't match its regexp.
+func (mux *Mux) URL(name string, args map[string]string) (string, error) {
+	mux.mu.RLock()
+	rt, ok := mux.namedRoutes[name]
+	mux.mu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrRouteNotFound, name)
+	}
+	return rt.buildURL(args)
+}
+
+// buildURL rebuild the path, extension and querys of a route with args
+func (rt *Route) buildURL(args map[string]string) (string, error) {
+	segments := make([]string, 0, len(rt.paths))
+loop:
+	for i, item := range rt.paths {
+		switch item.(type) {
+		case *catchAll:
+			// the catch-all segment can be omitted, and contains slashes
+			if rest := args[item.(*catchAll).Name]; rest != "" {
+				for _, segment := range strings.Split(rest, "/") {
+					segments = append(segments, url.PathEscape(segment))
+				}
+			}
+			continue
+
+		case *namedRegexp:
+			// the optional segment and the followings are omitted if the argument is missing
+			if nr := item.(*namedRegexp); nr.Optional {
+				if _, ok := args[nr.Name]; !ok {
+					break loop
+				}
+			}
+		}
+
+		segment, err := rt.buildSingle(item, args)
+		if err != nil {
+			return "", fmt.Errorf("sexrt: route %q path %d: %w", rt.name, i, err)
+		}
+		segments = append(segments, url.PathEscape(segment))
+	}
+	u := "/" + strings.Join(segments, "/")
+
+	// the extension only works when there is at least one path segment
+	if len(rt.exts) > 0 && len(segments) > 0 {
+		ext, err := rt.buildSlice(rt.exts, args)
+		if err != nil {
+			return "", fmt.Errorf("sexrt: route %q ext: %w", rt.name, err)
+		}
+		if ext != "" {
+			u += "." + url.PathEscape(ext)
+		}
+	}
+
+	if len(rt.querys) > 0 {
+		keys := make([]string, 0, len(rt.querys))
+		for k := range rt.querys {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		values := make(url.Values, len(keys))
+		for _, k := range keys {
+			value, err := rt.buildSlice(rt.querys[k], args)
+			if err != nil {
+				return "", fmt.Errorf("sexrt: route %q query %q: %w", rt.name, k, err)
+			}
+			values.Set(k, value)
+		}
+		u += "?" + values.Encode()
+	}
+
+	return u, nil
+}
+
+// buildSlice build the first item of a route slice which can be built by args
+func (rt *Route) buildSlice(slice []interface{}, args map[string]string) (s string, err error) {
+	for _, item := range slice {
+		if s, err = rt.buildSingle(item, args); err == nil {
+			return
+		}
+	}
+	return
+}
+
+// buildSingle build a route item, the literal string is returned directly and
+// the named regexp is substituted by args
+func (rt *Route) buildSingle(item interface{}, args map[string]string) (string, error) {
+	switch item.(type) {
+	case string:
+		return item.(string), nil
+
+	case *regexp.Regexp:
+		return "", fmt.Errorf("unnamed regexp %q can't be built", item.(*regexp.Regexp).String())
+
+	case *segmentTemplate:
+		tpl := item.(*segmentTemplate)
+		return rt.buildTemplate(tpl.Regexp, tpl.parts, args)
+
+	case *namedRegexp:
+		nr := item.(*namedRegexp)
+
+		value, ok := args[nr.Name]
+		if !ok {
+			return "", fmt.Errorf("missing argument %q", nr.Name)
+		}
+		if nr.conv != nil {
+			if _, err := nr.conv.convert(value); err != nil {
+				return "", fmt.Errorf("argument %q: %w", nr.Name, err)
+			}
+			return value, nil
+		}
+		if !rt.regexp(nr.Regexp).MatchString(value) {
+			return "", fmt.Errorf("argument %q value %q doesn't match %q", nr.Name, value, nr.String())
+		}
+		return value, nil
+
+	default:
+		panic("Unknow type of slice item")
+	}
+}
@@ -0,0 +1,91 @@
+package sexrt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMuxURL(t *testing.T) {
+	mux := NewMux()
+	mux.NewRoute().Name("index").Func(testHandler)
+	mux.NewRoute().Name("user").Path("user", `{name:^\w+$}`).Func(testHandler)
+	mux.NewRoute().Name("page").Path("page", `{id:^\d+$}`).Ext("html", "txt").Func(testHandler)
+	mux.NewRoute().Name("noext").Path("page").Ext("").Func(testHandler)
+	mux.NewRoute().Name("search").Path("search").
+		Query("q", `{q:^\w+$}`, "lang", "en", "lang", "zh").
+		Func(testHandler)
+	mux.NewRoute().Name("unnamed").Path(`{^\d+$}`).Func(testHandler)
+	mux.NewRoute().Name("files").Path("files", `{rest:*}`).Func(testHandler)
+	mux.NewRoute().Name("list").Path("list", `{page?:^\d+$}`, `{size?:^\d+$}`).Func(testHandler)
+
+	tests := []struct {
+		name string
+		args map[string]string
+		url  string
+	}{
+		{"index", nil, "/"},
+		{"user", map[string]string{"name": "jmjoy"}, "/user/jmjoy"},
+		{"page", map[string]string{"id": "123"}, "/page/123.html"},
+		{"noext", nil, "/page"},
+		{"search", map[string]string{"q": "golang"}, "/search?lang=en&q=golang"},
+		{"files", nil, "/files"},
+		{"files", map[string]string{"rest": "a/b c/d"}, "/files/a/b%20c/d"},
+		{"list", nil, "/list"},
+		{"list", map[string]string{"page": "2"}, "/list/2"},
+		{"list", map[string]string{"page": "2", "size": "10"}, "/list/2/10"},
+		{"list", map[string]string{"size": "10"}, "/list"},
+	}
+	for _, test := range tests {
+		u, err := mux.URL(test.name, test.args)
+		if err != nil {
+			t.Fatal(test.name+":", err)
+		}
+		if u != test.url {
+			t.Fatalf("%s: url %q not equal %q", test.name, u, test.url)
+		}
+	}
+
+	errTests := []struct {
+		name string
+		args map[string]string
+	}{
+		{"user", nil},
+		{"user", map[string]string{"name": "jm/joy"}},
+		{"page", map[string]string{"id": "abc"}},
+		{"search", map[string]string{}},
+		{"unnamed", nil},
+		{"list", map[string]string{"page": "x"}},
+	}
+	for _, test := range errTests {
+		if u, err := mux.URL(test.name, test.args); err == nil {
+			t.Fatalf("%s: %v built to %q without error", test.name, test.args, u)
+		}
+	}
+
+	if _, err := mux.URL("none", nil); !errors.Is(err, ErrRouteNotFound) {
+		t.Fatal("unknown route name not reported:", err)
+	}
+}
+
+func TestMuxURLRoundTrip(t *testing.T) {
+	mux := NewMux()
+	mux.NewRoute().Name("user").Path("user", `{name:^\w+$}`).Ext("html").Func(func(ctx *Ctx) error {
+		_, err := ctx.W.Write([]byte("hello:" + ctx.Args["name"]))
+		return err
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	u, err := mux.URL("user", map[string]string{"name": "jmjoy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testHTTPResponse("GET", srv.URL+u, "", func(body string, resp *http.Response) {
+		if body != "hello:jmjoy" {
+			t.Fatal(u + ": body not correct!")
+		}
+	})
+}
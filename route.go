@@ -1,6 +1,8 @@
 package sexrt
 
 import (
+	"errors"
+	"net/textproto"
 	"regexp"
 	"strings"
 )
@@ -8,29 +10,80 @@ import (
 type namedRegexp struct {
 	Name string
 	*regexp.Regexp
+	Optional bool       // optional trailing path segment (e.g. "{page?:\d+}")
+	conv     *converter // converter of typed argument (e.g. "{id:int}")
+}
+
+// catchAll is a path segment which match all the remaining segments (e.g. "{rest:*}")
+type catchAll struct {
+	Name string
 }
 
 // Route is a set of rules for matching a request
 type Route struct {
 	mux *Mux
 
+	name     string // route name used for reverse url building
+	priority int    // higher priority route is matched first
+
 	paths   []interface{}            // request.URL splits by "/" (e.g. "/hello/world" => ["hello", "world"])
 	methods []interface{}            // request Method (e.g. "GET", "POST", "PUT", "DELETE")
 	exts    []interface{}            // url extension (e.g. "html", "jpg", "pdf")
 	hosts   []interface{}            // the Host in request header
+	schemes []interface{}            // the scheme the request arrived over (e.g. "http", "https")
 	querys  map[string][]interface{} // url querys pair (e.g. "?a=1" => [a: 1])
 	headers map[string][]interface{} // request header pair (e.g. "Accept: XXX" => [Accept: XXX])
+
+	matchers []*customMatcher // user defined predicates
+	produces []string         // response media types (e.g. "application/json")
+	consumes []string         // request media types (e.g. "application/json")
+
+	middlewares []Middleware // wrap the handlers of routes built from it
+
+	rejectInvalidArgs bool // respond 400 instead of mismatch when typed argument conversion fails
+	headerFold        bool // match the header values case-insensitively
+
+	errs []*RouteError // the building errors, see Route.Err
+
+	anchors map[*regexp.Regexp]*regexp.Regexp // the anchored regexps of a registered route, see Mux.AnchorRegexps
 }
 
-// Path add some url segment to a building route, the order is important
+// Name set the name of a building route, so that the url can be built by Mux.URL.
+// The name is unique in a Mux, the route with a registered name is not registered, see Mux.Validate.
+func (rt *Route) Name(name string) *Route {
+	rt.name = name
+	return rt
+}
+
+// Priority set the priority of a building route, the route with higher priority
+// is matched first, routes with the same priority are matched in registration order
+func (rt *Route) Priority(priority int) *Route {
+	rt.priority = priority
+	return rt
+}
+
+// Path add some url segment to a building route, the order is important.
+// The catch-all segment "{name:*}" match all the remaining segments, it must be the last one,
+// and the optional segments "{name?:regexp}" can only be followed by optional segments.
+// The segment can mix the literal text and several captures (e.g. "v{major:\d+}.{minor:\d+}", "{name}@{domain}").
 func (rt *Route) Path(s ...string) *Route {
-	rt.paths = append(rt.paths, parseAppendString(s...)...)
+	rt.paths = rt.parseAppend("Path", rt.paths, s...)
+	if !rt.hasError("Path") {
+		if i, err := validatePaths(rt.paths); err != nil {
+			// the misplaced segment may be added by the former calls, blame the first argument then
+			offset := len(rt.paths) - len(s)
+			if i < offset {
+				i = offset
+			}
+			rt.addError("Path", patternSingle(rt.paths[i]), i-offset, err)
+		}
+	}
 	return rt
 }
 
 // Method add some reqeust method to a building route
 func (rt *Route) Method(s ...string) *Route {
-	rt.methods = append(rt.methods, parseAppendString(s...)...)
+	rt.methods = rt.parseAppend("Method", rt.methods, s...)
 	return rt
 }
 
@@ -56,7 +109,7 @@ func (rt *Route) Delete() *Route {
 
 // Ext add some url extension to a building route
 func (rt *Route) Ext(s ...string) *Route {
-	rt.exts = append(rt.exts, parseAppendString(s...)...)
+	rt.exts = rt.parseAppend("Ext", rt.exts, s...)
 	return rt
 }
 
@@ -67,46 +120,103 @@ func (rt *Route) Query(s ...string) *Route {
 	}
 
 	for i := 0; i < len(s); i += 2 {
-		rt.querys[s[i]] = append(rt.querys[s[i]], parseAppendString(s[i+1])[0])
+		if i+1 == len(s) {
+			rt.addError("Query", s[i], i, errMissingValue)
+			break
+		}
+
+		item, err := parseString(s[i+1])
+		if err != nil {
+			rt.addError("Query", s[i+1], i+1, err)
+			continue
+		}
+		rt.querys[s[i]] = append(rt.querys[s[i]], item)
 	}
 
 	return rt
 }
 
-// Host add some host name to a building route
+// Host add some host name to a building route, the host names are matched case-insensitively
+// and the port is ignored unless it is included. The dot-segmented host template
+// (e.g. "{tenant:[a-z]+}.example.com") capture the arguments, and the whole regexp host
+// (e.g. "{^\w+\.com:\d+$}") match the raw Host.
 func (rt *Route) Host(s ...string) *Route {
-	rt.hosts = append(rt.hosts, parseAppendString(s...)...)
+	rt.hosts = rt.parseHosts(s...)
 	return rt
 }
 
-// Header add some request header pair to a building route
+// Header add some request header pair to a building route, the header names are canonicalized
+// (e.g. "accept" => "Accept"), and the comma-separated list headers (e.g. "Accept", "Cache-Control")
+// are also matched by each token
 func (rt *Route) Header(s ...string) *Route {
 	if rt.headers == nil {
 		rt.headers = make(map[string][]interface{})
 	}
 
 	for i := 0; i < len(s); i += 2 {
-		rt.headers[s[i]] = append(rt.headers[s[i]], parseAppendString(s[i+1])[0])
+		if i+1 == len(s) {
+			rt.addError("Header", s[i], i, errMissingValue)
+			break
+		}
+
+		key := textproto.CanonicalMIMEHeaderKey(s[i])
+		item, err := parseString(s[i+1])
+		if err != nil {
+			rt.addError("Header", s[i+1], i+1, err)
+			continue
+		}
+		if rt.headerFold {
+			item = foldRouteSingle(item)
+		}
+		rt.headers[key] = append(rt.headers[key], item)
 	}
 
 	return rt
 }
 
+// IgnoreHeaderCase will make a building route match the header values case-insensitively
+func (rt *Route) IgnoreHeaderCase() *Route {
+	rt.headerFold = true
+	for k, slice := range rt.headers {
+		for i := range slice {
+			slice[i] = foldRouteSingle(slice[i])
+		}
+		rt.headers[k] = slice
+	}
+	return rt
+}
+
 // Func will always deep clone the route and registe it into relative Mux
 func (rt *Route) Func(fn routeHandler) {
 	newRoute := rt.clone()
-	rt.mux.routeHandlerPool[newRoute] = fn
+	if rt.mux.isAnchored() {
+		newRoute.anchor()
+	}
+	rt.mux.addRoute(newRoute, wrapMiddlewares(fn, newRoute.middlewares))
 }
 
 func (rt *Route) clone() *Route {
 	return &Route{
-		mux:     rt.mux,
-		paths:   cloneRouteSlice(rt.paths),
-		methods: cloneRouteSlice(rt.methods),
-		exts:    cloneRouteSlice(rt.exts),
-		hosts:   cloneRouteSlice(rt.hosts),
-		querys:  cloneRouteMap(rt.querys),
-		headers: cloneRouteMap(rt.headers),
+		mux:      rt.mux,
+		name:     rt.name,
+		priority: rt.priority,
+		paths:    cloneRouteSlice(rt.paths),
+		methods:  cloneRouteSlice(rt.methods),
+		exts:     cloneRouteSlice(rt.exts),
+		hosts:    cloneRouteSlice(rt.hosts),
+		schemes:  cloneRouteSlice(rt.schemes),
+		querys:   cloneRouteMap(rt.querys),
+		headers:  cloneRouteMap(rt.headers),
+		matchers: cloneMatchers(rt.matchers),
+		produces: append([]string(nil), rt.produces...),
+		consumes: append([]string(nil), rt.consumes...),
+
+		middlewares: append([]Middleware(nil), rt.middlewares...),
+
+		rejectInvalidArgs: rt.rejectInvalidArgs,
+		headerFold:        rt.headerFold,
+
+		errs: append([]*RouteError(nil), rt.errs...),
 	}
 }
 
@@ -123,10 +233,24 @@ func cloneRouteSingle(item interface{}) (newItem interface{}) {
 		nr := item.(*namedRegexp)
 		reg := *nr.Regexp
 		newItem = &namedRegexp{
-			Name:   nr.Name,
-			Regexp: &reg,
+			Name:     nr.Name,
+			Regexp:   &reg,
+			Optional: nr.Optional,
+			conv:     nr.conv,
 		}
 
+	case *catchAll:
+		ca := *(item.(*catchAll))
+		newItem = &ca
+
+	case *hostTemplate:
+		ht := *(item.(*hostTemplate))
+		newItem = &ht
+
+	case *segmentTemplate:
+		tpl := *(item.(*segmentTemplate))
+		newItem = &tpl
+
 	default:
 		panic("Unknow type of slice item")
 	}
@@ -134,6 +258,36 @@ func cloneRouteSingle(item interface{}) (newItem interface{}) {
 	return
 }
 
+// foldRouteSingle make the regexp of a route item case-insensitive,
+// the literal strings are compared by strings.EqualFold when matching
+func foldRouteSingle(item interface{}) interface{} {
+	switch item.(type) {
+	case *regexp.Regexp:
+		return foldRegexp(item.(*regexp.Regexp))
+
+	case *namedRegexp:
+		nr := item.(*namedRegexp)
+		if nr.conv != nil {
+			return nr
+		}
+		return &namedRegexp{
+			Name:     nr.Name,
+			Regexp:   foldRegexp(nr.Regexp),
+			Optional: nr.Optional,
+		}
+
+	default:
+		return item
+	}
+}
+
+func foldRegexp(reg *regexp.Regexp) *regexp.Regexp {
+	if strings.HasPrefix(reg.String(), "(?i)") {
+		return reg
+	}
+	return regexp.MustCompile("(?i)" + reg.String())
+}
+
 func cloneRouteSlice(slice []interface{}) []interface{} {
 	if slice == nil {
 		return nil
@@ -162,32 +316,116 @@ func cloneRouteMap(m map[string][]interface{}) map[string][]interface{} {
 	return newM
 }
 
-func parseAppendString(s ...string) []interface{} {
-	newSlice := make([]interface{}, 0, len(s))
+// parseAppend parse the arguments of a builder method and append the route items to dst,
+// the invalid arguments are recorded as the errors of this route
+func (rt *Route) parseAppend(field string, dst []interface{}, s ...string) []interface{} {
+	parse := parseString
+	if field == "Path" {
+		parse = parseSegment
+	}
 
-	for _, str := range s {
-		if !strings.HasPrefix(str, "{") || !strings.HasSuffix(str, "}") {
-			// common string, use `==` to validate
-			newSlice = append(newSlice, str)
+	for i, str := range s {
+		item, err := parse(str)
+		if err != nil {
+			rt.addError(field, str, i, err)
 			continue
 		}
+		dst = append(dst, item)
+	}
+	return dst
+}
+
+// parseSegment parse a path segment into a route item, the segment mixing the literal text
+// and the captures (e.g. "user-{id:\d+}") is compiled to a segmentTemplate
+func parseSegment(str string) (interface{}, error) {
+	if isTemplate(str) {
+		return compileSegmentTemplate(str)
+	}
+	return parseString(str)
+}
+
+// parseString parse a pattern string into a route item
+func parseString(str string) (interface{}, error) {
+	if !strings.HasPrefix(str, "{") || !strings.HasSuffix(str, "}") {
+		// common string, use `==` to validate
+		return str, nil
+	}
+
+	// regexp string, validate by regexp
+	// remove `{ }`
+	str = str[1 : len(str)-1]
+
+	// unnamed catch-all
+	if str == "*" {
+		return &catchAll{}, nil
+	}
+
+	// check the ":" is not at the first or last position, and the text before it is a name,
+	// otherwise it is a part of the regexp (e.g. "{^\w+\.com:\d+$}")
+	if index := strings.Index(str, ":"); index > 0 && index < len(str)-1 &&
+		identRegexp.MatchString(strings.TrimSuffix(str[:index], "?")) {
+		name, pattern := str[:index], str[index+1:]
+
+		// named catch-all
+		if pattern == "*" {
+			return &catchAll{Name: name}, nil
+		}
 
-		// regexp string, validate by regexp
-		// remove `{ }`
-		str = str[1 : len(str)-1]
+		// typed argument (e.g. "{id:int}")
+		if conv, ok := converters[pattern]; ok {
+			return &namedRegexp{
+				Name:     strings.TrimSuffix(name, "?"),
+				Regexp:   conv.Regexp,
+				Optional: strings.HasSuffix(name, "?"),
+				conv:     conv,
+			}, nil
+		}
 
-		// check the ":" is not at the first or last position
-		if index := strings.Index(str, ":"); index > 0 && index < len(str)-1 {
-			// named regexp string
-			newSlice = append(newSlice, &namedRegexp{
-				Name:   str[:index],
-				Regexp: regexp.MustCompile(str[index+1:]),
-			})
+		// named regexp string, the name ends with "?" means optional
+		reg, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return &namedRegexp{
+			Name:     strings.TrimSuffix(name, "?"),
+			Regexp:   reg,
+			Optional: strings.HasSuffix(name, "?"),
+		}, nil
+	}
+	// unmamed regexp string
+	reg, err := regexp.Compile(str)
+	if err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// validatePaths check the catch-all segment is the last one,
+// and the optional segments are only followed by optional segments,
+// return the position of the misplaced segment
+func validatePaths(paths []interface{}) (int, error) {
+	optional := false
+
+	for i, item := range paths {
+		if _, ok := item.(*catchAll); ok {
+			if i != len(paths)-1 {
+				return i, errors.New("catch-all segment must be the last path element")
+			}
+			if optional {
+				return i, errors.New("catch-all segment can't follow optional segments")
+			}
 			continue
 		}
-		// unmamed regexp string
-		newSlice = append(newSlice, regexp.MustCompile(str))
+
+		if nr, ok := item.(*namedRegexp); ok && nr.Optional {
+			optional = true
+			continue
+		}
+
+		if optional {
+			return i, errors.New("optional segments can only be followed by optional segments")
+		}
 	}
 
-	return newSlice
+	return -1, nil
 }
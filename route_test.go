@@ -27,9 +27,9 @@ var testRoute0 = &Route{
 		"key1": []interface{}{"value1", &namedRegexp{Name: "name0", Regexp: regexp.MustCompile(`^\d+$`)}},
 	},
 	headers: map[string][]interface{}{
-		"key":  []interface{}{"value"},
-		"key0": []interface{}{"value0", regexp.MustCompile(`^\d+$`)},
-		"key1": []interface{}{"value1", &namedRegexp{Name: "name0", Regexp: regexp.MustCompile(`^\d+$`)}},
+		"Key":  []interface{}{"value"},
+		"Key0": []interface{}{"value0", regexp.MustCompile(`^\d+$`)},
+		"Key1": []interface{}{"value1", &namedRegexp{Name: "name0", Regexp: regexp.MustCompile(`^\d+$`)}},
 	},
 }
 
@@ -80,7 +80,8 @@ func TestRouteFunc(t *testing.T) {
 		t.Fatal("len of routeHandlerPool isn't 1")
 	}
 
-	for testRt, testFn := range mux.routeHandlerPool {
+	for _, entry := range mux.routeHandlerPool {
+		testRt, testFn := entry.route, entry.fn
 		t.Logf("%p, %p", rt, testRt)
 		if rt == testRt {
 			t.Fatal("not a new object")
@@ -161,10 +162,50 @@ func TestCloneRouteMap(t *testing.T) {
 	}
 }
 
-func TestParseAppendString(t *testing.T) {
-	result := parseAppendString(testStr0...)
+func TestParseAppend(t *testing.T) {
+	result := new(Route).parseAppend("Path", nil, testStr0...)
 	t.Logf("%+v, %+v", testParsed0, result)
 	if !reflect.DeepEqual(result, testParsed0) {
 		t.Fatal("not equal")
 	}
 }
+
+func TestParseAppendWildcard(t *testing.T) {
+	result := new(Route).parseAppend("Path", nil, `{rest:*}`, `{*}`, `{page?:^\d+$}`)
+	expect := []interface{}{
+		&catchAll{Name: "rest"},
+		&catchAll{},
+		&namedRegexp{Name: "page", Regexp: regexp.MustCompile(`^\d+$`), Optional: true},
+	}
+	if !reflect.DeepEqual(result, expect) {
+		t.Fatal("not equal")
+	}
+
+	// the text before ":" isn't a name
+	item, err := parseString(`{^\w+\.com:\d+$}`)
+	if reg, ok := item.(*regexp.Regexp); err != nil || !ok || reg.String() != `^\w+\.com:\d+$` {
+		t.Fatal("whole regexp parsed as a named one:", item, err)
+	}
+
+	if !reflect.DeepEqual(cloneRouteSlice(result), expect) {
+		t.Fatal("not deep equal")
+	}
+}
+
+func TestRoutePathValidate(t *testing.T) {
+	invalids := [][]string{
+		{`{rest:*}`, "x"},
+		{`{page?:\d+}`, "x"},
+		{`{page?:\d+}`, `{rest:*}`},
+	}
+	for _, paths := range invalids {
+		if new(Route).Path(paths...).Err() == nil {
+			t.Fatalf("%v: error not recorded", paths)
+		}
+	}
+
+	if new(Route).Path("x", `{page?:\d+}`, `{size?:\d+}`).Err() != nil ||
+		new(Route).Path("x").Path(`{rest:*}`).Err() != nil {
+		t.Fatal("valid paths with error")
+	}
+}
@@ -0,0 +1,158 @@
+package sexrt
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Scheme add some scheme to a building route (e.g. "http", "https"), the scheme of request is
+// "https" if it arrived over TLS, or the one forwarded by the trusted proxies, see Mux.TrustProxies.
+// The route with the "https" scheme is secure, see Mux.RedirectToHTTPS.
+func (rt *Route) Scheme(s ...string) *Route {
+	for _, item := range rt.parseAppend("Scheme", nil, s...) {
+		if scheme, ok := item.(string); ok {
+			item = strings.ToLower(scheme)
+		}
+		rt.schemes = append(rt.schemes, item)
+	}
+	return rt
+}
+
+// TrustProxies set the proxies whose "Forwarded" and "X-Forwarded-Proto" headers are trusted,
+// the proxies are CIDRs (e.g. "10.0.0.0/8") or IP addresses, and it panics if one is invalid
+func (mux *Mux) TrustProxies(cidrs ...string) {
+	proxies := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				panic("sexrt: invalid proxy address " + cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic("sexrt: invalid proxy CIDR " + cidr)
+		}
+		proxies = append(proxies, ipNet)
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.trustedProxies = proxies
+}
+
+// RedirectToHTTPS will make this Mux redirect the request to https, when it arrived over http
+// and match a secure route except the scheme
+func (mux *Mux) RedirectToHTTPS(on bool) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.redirectToHTTPS = on
+}
+
+// isSecure report whether the route is served over https
+func (rt *Route) isSecure() bool {
+	for _, item := range rt.schemes {
+		if item == "https" {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrustedProxy check the request is sent by a trusted proxy
+func (mux *Mux) isTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range mux.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestScheme return the scheme used for matching the schemes of routes,
+// the "Forwarded" header is preferred to "X-Forwarded-Proto"
+func (mux *Mux) requestScheme(r *http.Request) string {
+	if len(mux.trustedProxies) > 0 && mux.isTrustedProxy(r) {
+		if proto := forwardedProto(r.Header.Get("Forwarded")); proto != "" {
+			return proto
+		}
+
+		// the first one is the scheme requested by the client
+		proto := r.Header.Get("X-Forwarded-Proto")
+		if i := strings.IndexByte(proto, ','); i >= 0 {
+			proto = proto[:i]
+		}
+		if proto = strings.TrimSpace(proto); proto != "" {
+			return strings.ToLower(proto)
+		}
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// forwardedProto return the "proto" parameter of the first element of the RFC 7239 "Forwarded" header
+// (e.g. "for=192.0.2.60;proto=https;by=203.0.113.43, for=198.51.100.17" => "https")
+func forwardedProto(forwarded string) string {
+	if i := strings.IndexByte(forwarded, ','); i >= 0 {
+		forwarded = forwarded[:i]
+	}
+
+	for _, pair := range strings.Split(forwarded, ";") {
+		i := strings.IndexByte(pair, '=')
+		if i < 0 || !strings.EqualFold(strings.TrimSpace(pair[:i]), "proto") {
+			continue
+		}
+		return strings.ToLower(strings.Trim(strings.TrimSpace(pair[i+1:]), `"`))
+	}
+	return ""
+}
+
+// redirectHTTPSHandler redirect the request to https, the method and body are kept
+// by "308 Permanent Redirect" except GET and HEAD. The forwarded host is only used
+// when the request is sent by a trusted proxy, otherwise it is an open redirect.
+func (mux *Mux) redirectHTTPSHandler(ctx *Ctx) error {
+	status := http.StatusPermanentRedirect
+	if ctx.R.Method == "GET" || ctx.R.Method == "HEAD" {
+		status = http.StatusMovedPermanently
+	}
+
+	// use the original RequestURI, the path is stripped when mounted
+	uri := ctx.R.URL.RequestURI()
+	if u, err := url.ParseRequestURI(ctx.R.RequestURI); err == nil {
+		uri = u.RequestURI()
+	}
+
+	mux.mu.RLock()
+	host := ctx.R.Host
+	if len(mux.trustedProxies) > 0 && mux.isTrustedProxy(ctx.R) {
+		host = ctx.host
+	}
+	mux.mu.RUnlock()
+
+	http.Redirect(ctx.W, ctx.R, "https://"+stripPort(host)+uri, status)
+	return nil
+}
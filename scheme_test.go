@@ -0,0 +1,126 @@
+package sexrt
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMuxRouteScheme(t *testing.T) {
+	mux := NewMux()
+	mux.NewRoute().Scheme("HTTPS").Path("secure").Func(testWriteHandler("secure"))
+	mux.NewRoute().Scheme("http").Path("plain").Func(testWriteHandler("plain"))
+
+	tests := []struct {
+		u      string
+		tls    bool
+		header map[string]string
+		remote string
+		status int
+		body   string
+	}{
+		{"/secure", true, nil, "", 200, "secure"},
+		{"/secure", false, nil, "", 404, ""},
+		{"/plain", false, nil, "", 200, "plain"},
+		{"/plain", true, nil, "", 404, ""},
+		// not a trusted proxy
+		{"/secure", false, map[string]string{"X-Forwarded-Proto": "https"}, "192.0.2.1:1234", 404, ""},
+		{"/secure", false, map[string]string{"X-Forwarded-Proto": "HTTPS, http"}, "10.1.2.3:1234", 200, "secure"},
+		{"/secure", false, map[string]string{"Forwarded": `for=192.0.2.60;proto="https", for=10.1.2.3`}, "10.1.2.3:1234", 200, "secure"},
+		{"/plain", false, map[string]string{"Forwarded": "proto=http", "X-Forwarded-Proto": "https"}, "127.0.0.1:1234", 200, "plain"},
+		{"/plain", true, map[string]string{"X-Forwarded-Proto": "http"}, "127.0.0.1:1234", 200, "plain"},
+	}
+
+	mux.TrustProxies("10.0.0.0/8", "127.0.0.1")
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.u, nil)
+		if !test.tls {
+			req.TLS = nil
+		} else {
+			req.TLS = &tls.ConnectionState{}
+		}
+		if test.remote != "" {
+			req.RemoteAddr = test.remote
+		}
+		for k, v := range test.header {
+			req.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != test.status || (test.status == 200 && w.Body.String() != test.body) {
+			t.Fatalf("%s %v: response %d %q not correct", test.u, test.header, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestMuxRedirectToHTTPS(t *testing.T) {
+	mux := NewMux()
+	mux.RedirectToHTTPS(true)
+	mux.NewRoute().Scheme("https").Path("login").Get().Post().Func(testWriteHandler("login"))
+	mux.NewRoute().Scheme("http").Path("plain").Func(testWriteHandler("plain"))
+
+	sub := NewMux()
+	sub.RedirectToHTTPS(true)
+	sub.NewRoute().Scheme("https").Path("account").Func(testWriteHandler("account"))
+	mux.NewRoute().Path("admin").Mount(sub)
+
+	tests := []struct {
+		method   string
+		u        string
+		status   int
+		location string
+	}{
+		{"GET", "http://example.com:8080/login?next=%2F", 301, "https://example.com/login?next=%2F"},
+		{"POST", "http://example.com/login", 308, "https://example.com/login"},
+		{"PUT", "http://example.com/login", 405, ""},
+		{"GET", "http://example.com/none", 404, ""},
+		{"GET", "http://example.com/admin/account", 301, "https://example.com/admin/account"},
+		// the http only route isn't redirected
+		{"GET", "https://example.com/plain", 404, ""},
+		{"GET", "http://example.com/plain", 200, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.u, nil))
+		if w.Code != test.status || w.Header().Get("Location") != test.location {
+			t.Fatalf("%s %s: response %d %q not correct", test.method, test.u, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	req := httptest.NewRequest("GET", "https://example.com/login", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Body.String() != "login" {
+		t.Fatal("/login: body not correct!")
+	}
+
+	// turned off
+	mux.RedirectToHTTPS(false)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/login", nil))
+	if w.Code != 404 {
+		t.Fatal("/login: redirected after turned off:", w.Code)
+	}
+	mux.RedirectToHTTPS(true)
+
+	// the forwarded host is only used from the trusted proxies
+	mux.TrustForwardedHost(true)
+	req = httptest.NewRequest("GET", "http://example.com/login", nil)
+	req.Header.Set("X-Forwarded-Host", "evil.com")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if location := w.Header().Get("Location"); location != "https://example.com/login" {
+		t.Fatal("/login: redirected to the forwarded host:", location)
+	}
+
+	// httptest.NewRequest is sent from 192.0.2.1
+	mux.TrustProxies("192.0.2.0/24")
+	req.Header.Set("X-Forwarded-Host", "www.example.com")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if location := w.Header().Get("Location"); location != "https://www.example.com/login" {
+		t.Fatal("/login: not redirected to the forwarded host:", location)
+	}
+}
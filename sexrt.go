@@ -1,54 +1,82 @@
 package sexrt
 
 import (
+	"log"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 )
 
 type routeHandler func(*Ctx) error
 
 // Ctx is a Context contains http request, response and regexp arguments.
 type Ctx struct {
-	R    *http.Request
-	W    http.ResponseWriter
-	Args map[string]string // regexp arguments
+	R     *http.Request
+	W     http.ResponseWriter
+	Args  map[string]string // regexp arguments
+	Route *Route            // the matched route, nil if not found
+
+	host   string                 // the host used for matching, see Mux.TrustForwardedHost
+	scheme string                 // the scheme used for matching, see Mux.TrustProxies
+	paths  []string               // request.URL splits by "/" without extension
+	ext    string                 // url extension
+	values map[string]interface{} // converted typed arguments
+
+	accept    []mediaRange // parsed "Accept" header, nil if not parsed yet
+	mediaType string       // the negotiated response media type, see Ctx.MediaType
 }
 
 // Mux is a http.Handler implementer
 type Mux struct {
 	*http.ServeMux
 
-	routeHandlerPool map[*Route]routeHandler
-
-	notFoundHandler routeHandler
-	errorHandler    func(error)
+	mu sync.RWMutex // protect the registered routes, so routes can be registered while serving
+
+	routeHandlerPool   []*routeEntry        // registered routes in matching order, copied on write
+	namedRoutes        map[string]*Route    // named routes for reverse url building
+	routeSeq           int                  // registration counter
+	mostSpecific       bool                 // sort routes by specificity before registration order
+	autoMethods        bool                 // answer OPTIONS and HEAD automatically
+	trustForwardedHost bool                 // match the hosts of routes against "X-Forwarded-Host"
+	trustedProxies     []*net.IPNet         // the proxies whose forwarded headers are trusted
+	redirectToHTTPS    bool                 // redirect the requests of secure routes to https
+	middlewares        []Middleware         // wrap all the handlers
+	notFoundPool       []*routeEntry        // not found handlers of route groups
+	routeErrs          []*RouteError        // errors of the routes which are not registered
+	reportedErrs       map[*RouteError]bool // the recorded building errors, which are shared by the subrouters
+	anchorRegexps      bool                 // anchor the regexps of routes registered later
+	index              *routeIndex          // segment trie of routeHandlerPool, built lazily
+
+	notFoundHandler         routeHandler // nil means the default one, or the outer Mux one when mounted
+	methodNotAllowedHandler routeHandler
+	errorHandler            func(*Ctx, error)
+	logger                  Logger
 }
 
 // NewMuxWithHandler will new a Mux witch user defined not found and error handler
-func NewMuxWithHandler(notFoundHandler routeHandler, errorHandler func(error)) *Mux {
-	if notFoundHandler == nil {
-		// default Not Found handler
-		notFoundHandler = func(ctx *Ctx) error {
-			http.NotFound(ctx.W, ctx.R)
+func NewMuxWithHandler(notFoundHandler routeHandler, errorHandler func(*Ctx, error)) *Mux {
+	mux := &Mux{
+		ServeMux:        http.NewServeMux(),
+		namedRoutes:     make(map[string]*Route),
+		notFoundHandler: notFoundHandler,
+		errorHandler:    errorHandler,
+		// default Method Not Allowed handler, the "Allow" header is already set
+		methodNotAllowedHandler: func(ctx *Ctx) error {
+			http.Error(ctx.W, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 			return nil
-		}
+		},
+		logger: log.New(os.Stderr, "", log.LstdFlags),
 	}
 
 	if errorHandler == nil {
 		// default error handler
-		errorHandler = func(err error) {
-			panic(err)
-		}
-	}
-
-	mux := &Mux{
-		ServeMux:         http.NewServeMux(),
-		routeHandlerPool: make(map[*Route]routeHandler),
-		notFoundHandler:  notFoundHandler,
-		errorHandler:     errorHandler,
+		mux.errorHandler = mux.defaultErrorHandler
 	}
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -57,13 +85,26 @@ func NewMuxWithHandler(notFoundHandler routeHandler, errorHandler func(error)) *
 			W:    w,
 			Args: make(map[string]string),
 		}
+		// the settings may be changed while serving
+		mux.mu.RLock()
+		ctx.host = mux.requestHost(r)
+		ctx.scheme = mux.requestScheme(r)
+		middlewares := mux.middlewares
+		mux.mu.RUnlock()
+
+		ctx.paths, ctx.ext = getPathsAndExt(r.URL)
+
+		// merge the captures of the outer Mux when mounted
+		for k, v := range ArgsFromRequest(r) {
+			ctx.Args[k] = v
+		}
 
 		// get handler and regexp args of a matchesd route
 		fn := mux.matchRoute(ctx)
+		withMatch(ctx)
+		fn = wrapMiddlewares(fn, middlewares)
 
-		if err := fn(ctx); err != nil {
-			mux.errorHandler(err)
-		}
+		mux.serve(ctx, fn)
 	})
 
 	return mux
@@ -79,112 +120,458 @@ func (mux *Mux) NewRoute() *Route {
 	return &Route{mux: mux}
 }
 
-// HandleNotFound will set user defined not found handler to this Mux
+// HandleNotFound will set user defined not found handler to this Mux,
+// nil means the default one, or the outer Mux one when this Mux is mounted
 func (mux *Mux) HandleNotFound(notFoundHandler routeHandler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
 	mux.notFoundHandler = notFoundHandler
 }
 
-// HandleError will set user defined error handler to this Mux
-func (mux *Mux) HandleError(errorHandler func(error)) {
+// Remove will unregister the route with the name, and report whether it is removed
+func (mux *Mux) Remove(name string) bool {
+	if name == "" {
+		// the unnamed routes can't be removed
+		return false
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	pool := make([]*routeEntry, 0, len(mux.routeHandlerPool))
+	for _, entry := range mux.routeHandlerPool {
+		if entry.route.name != name {
+			pool = append(pool, entry)
+		}
+	}
+	if len(pool) == len(mux.routeHandlerPool) {
+		return false
+	}
+
+	mux.routeHandlerPool = pool
+	mux.index = nil
+	delete(mux.namedRoutes, name)
+	return true
+}
+
+// Replace will replace the handler of the route with the name, the middlewares of the route are kept,
+// and report whether it is replaced
+func (mux *Mux) Replace(name string, fn routeHandler) bool {
+	if name == "" {
+		// the unnamed routes can't be replaced
+		return false
+	}
+
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	replaced := false
+	pool := append([]*routeEntry(nil), mux.routeHandlerPool...)
+	for i, entry := range pool {
+		if entry.route.name == name {
+			pool[i] = &routeEntry{
+				route: entry.route,
+				fn:    wrapMiddlewares(fn, entry.route.middlewares),
+				seq:   entry.seq,
+			}
+			replaced = true
+		}
+	}
+	if replaced {
+		mux.routeHandlerPool = pool
+	}
+	return replaced
+}
+
+// AutoMethods will make this Mux answer OPTIONS with the methods registered for the path,
+// and serve HEAD by the matched GET route with the body discarded
+func (mux *Mux) AutoMethods(on bool) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.autoMethods = on
+}
+
+// HandleMethodNotAllowed will set user defined method not allowed handler to this Mux,
+// the "Allow" header is set before the handler is called
+func (mux *Mux) HandleMethodNotAllowed(methodNotAllowedHandler routeHandler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.methodNotAllowedHandler = methodNotAllowedHandler
+}
+
+// HandleError will set user defined error handler to this Mux,
+// it is called with the Ctx when a handler return an error
+func (mux *Mux) HandleError(errorHandler func(*Ctx, error)) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
 	mux.errorHandler = errorHandler
 }
 
+// matchLevel is how far a route matches a request
+type matchLevel int
+
+const (
+	matchNone          matchLevel = iota // mismatch on paths, host, ext, querys, headers or predicates
+	matchMethod                          // match everything except the method
+	matchUnsupported                     // match everything except the request media type
+	matchNotAcceptable                   // match everything except the response media type
+	matchScheme                          // match everything except the scheme
+	matchFull                            // fully match
+)
+
 // matchRoute find a route which match the request
 func (mux *Mux) matchRoute(ctx *Ctx) routeHandler {
-	// find a matched route
-	for rt := range mux.routeHandlerPool {
-		if is := isRouteMatch(rt, ctx); is {
-			return mux.routeHandlerPool[rt]
+	method := ctx.R.Method
+
+	entry, level, allowed := mux.findRoute(ctx, method)
+	if entry != nil {
+		return entry.fn
+	}
+
+	mux.mu.RLock()
+	autoMethods, methodNotAllowedHandler := mux.autoMethods, mux.methodNotAllowedHandler
+	mux.mu.RUnlock()
+
+	if autoMethods && level == matchMethod {
+		switch method {
+		case "HEAD":
+			// serve HEAD by the GET route and discard the body
+			if entry, _, _ := mux.findRoute(ctx, "GET"); entry != nil {
+				return func(ctx *Ctx) error {
+					ctx.W = headResponseWriter{ctx.W}
+					return entry.fn(ctx)
+				}
+			}
+
+		case "OPTIONS":
+			ctx.W.Header().Set("Allow", strings.Join(uniqueSorted(autoAllowed(allowed)), ", "))
+			return optionsHandler
 		}
+
+		allowed = autoAllowed(allowed)
+	}
+
+	switch level {
+	case matchScheme:
+		// the route is only served over https
+		return mux.redirectHTTPSHandler
+
+	case matchNotAcceptable:
+		// the answer depends on the "Accept" header too, as the negotiated one
+		ctx.W.Header().Add("Vary", "Accept")
+		return notAcceptableHandler
+
+	case matchUnsupported:
+		return unsupportedMediaTypeHandler
+
+	case matchMethod:
+		// method not allowed
+		ctx.W.Header().Set("Allow", strings.Join(uniqueSorted(allowed), ", "))
+		return methodNotAllowedHandler
 	}
 
 	// not found
-	return mux.notFoundHandler
+	return mux.findNotFound(ctx)
 }
 
-// isRouteMatch check the request is match a route in global route-function map
-func isRouteMatch(rt *Route, ctx *Ctx) (is bool) {
-	r := ctx.R
-	args := ctx.Args
+// findRoute find the first route which match the request with the method in priority order,
+// the routes with the same priority which produce media types are negotiated by the client preference.
+// If not found, return the best mismatch level of routes, and the methods of the routes which match
+// everything except the method.
+func (mux *Mux) findRoute(ctx *Ctx, method string) (entry *routeEntry, level matchLevel, allowed []string) {
+	// match the routes outside the lock, so that the user defined predicates can call back into the Mux
+	index := mux.rlockIndex()
+	pool, redirectToHTTPS := mux.routeHandlerPool, mux.redirectToHTTPS
+	mux.mu.RUnlock()
+
+	// the arguments are staged per candidate route,
+	// and only committed to ctx.Args when the route fully matches
+	st, best := newMatchState(), newMatchState()
+	var found *routeEntry
 
-	// check method
-	if len(rt.methods) > 0 {
-		if !isSliceMatch(rt.methods, r.Method, args) {
-			return
+loop:
+	for _, i := range index.lookup(ctx.paths) {
+		entry = pool[i]
+		if found != nil && (entry.route.priority != found.route.priority || len(entry.route.produces) == 0) {
+			continue
 		}
+		st.reset(entry.route)
+
+		switch l := isRouteMatch(entry.route, ctx, method, st); l {
+		case matchFull:
+			if found != nil && st.quality <= best.quality {
+				continue
+			}
+			found = entry
+			st, best = best, st
+			if len(found.route.produces) == 0 || best.quality == 1 {
+				break loop
+			}
+
+		case matchMethod:
+			allowed = appendMethods(allowed, entry.route.methods, st)
+			if l > level {
+				level = l
+			}
+
+		case matchScheme:
+			// only the requests arrived over http are redirected to the secure routes,
+			// otherwise it is a mismatch
+			if redirectToHTTPS && ctx.scheme == "http" && entry.route.isSecure() && l > level {
+				level = l
+			}
+
+		default:
+			if l > level {
+				level = l
+			}
+		}
+	}
+
+	if found == nil {
+		return nil, level, allowed
+	}
+
+	best.commit(ctx)
+	ctx.Route = found.route
+	if len(found.route.produces) > 0 {
+		ctx.W.Header().Add("Vary", "Accept")
 	}
+	if best.err != nil {
+		// typed argument conversion failure of a route which rejects invalid arguments
+		return &routeEntry{route: found.route, fn: badRequestHandler(best.err)}, matchFull, nil
+	}
+	return found, matchFull, nil
+}
+
+// rlockIndex acquire the read lock of registered routes, and build the index if needed
+func (mux *Mux) rlockIndex() *routeIndex {
+	mux.mu.RLock()
+	for mux.index == nil {
+		mux.mu.RUnlock()
+		mux.mu.Lock()
+		if mux.index == nil {
+			mux.index = newRouteIndex(mux.routeHandlerPool)
+		}
+		mux.mu.Unlock()
+		mux.mu.RLock()
+	}
+	return mux.index
+}
+
+// matchState is the staged result of matching a candidate route
+type matchState struct {
+	args   map[string]string      // captured arguments
+	values map[string]interface{} // converted typed arguments
+	strict bool                   // typed argument conversion failure is an error instead of a mismatch
+	err    error                  // the first typed argument conversion failure
+
+	mediaType string  // the negotiated response media type
+	quality   float64 // the client preference of mediaType
+
+	route *Route // the candidate route, whose anchored regexps are used
+}
+
+func newMatchState() *matchState {
+	return &matchState{
+		args:   make(map[string]string),
+		values: make(map[string]interface{}),
+	}
+}
+
+// reset clear the staged result for matching the route
+func (st *matchState) reset(rt *Route) {
+	for k := range st.args {
+		delete(st.args, k)
+	}
+	for k := range st.values {
+		delete(st.values, k)
+	}
+	st.strict = rt.rejectInvalidArgs
+	st.err = nil
+	st.mediaType, st.quality = "", 0
+	st.route = rt
+}
+
+// commit copy the staged result to the ctx
+func (st *matchState) commit(ctx *Ctx) {
+	for k, v := range st.args {
+		ctx.Args[k] = v
+	}
+	if len(st.values) > 0 && ctx.values == nil {
+		ctx.values = make(map[string]interface{}, len(st.values))
+	}
+	for k, v := range st.values {
+		ctx.values[k] = v
+	}
+	ctx.mediaType = st.mediaType
+}
+
+// isRouteMatch check the request is match a route, the captured arguments are staged into st
+func isRouteMatch(rt *Route, ctx *Ctx, method string, st *matchState) matchLevel {
+	r := ctx.R
 
 	// check host
 	if len(rt.hosts) > 0 {
-		if !isSliceMatch(rt.hosts, r.Host, args) {
-			return
+		if !isHostMatch(rt.hosts, ctx.host, st) {
+			return matchNone
 		}
 	}
 
-	// parse paths and ext
-	paths, ext := getPathsAndExt(r.URL)
+	paths, ext := ctx.paths, ctx.ext
 
-	// check paths
-	if len(rt.paths) != len(paths) {
-		return
-	}
-	for i := range rt.paths {
-		if !isSingleMatch(rt.paths[i], paths[i], args) {
-			return
+	// check paths, the last segment template of the route without extensions match the raw segment first,
+	// so that its captures can contain the dots (e.g. "{name}@{domain}")
+	if len(rt.exts) == 0 && isTemplateExtMatch(rt.paths, paths, ext, st) {
+		ext = ""
+	} else if !isPathsMatch(rt.paths, paths, ext, st) {
+		if !isTemplateExtMatch(rt.paths, paths, ext, st) {
+			return matchNone
 		}
+		ext = ""
 	}
 
 	// check extension
 	if len(rt.exts) > 0 && len(paths) > 0 {
-		if !isSliceMatch(rt.exts, ext, args) {
-			return
+		if !isSliceMatch(rt.exts, ext, st) {
+			return matchNone
 		}
 	}
 
 	// check querys
 	if len(rt.querys) > 0 {
-		if !isMapMatch(rt.querys, r.URL.Query(), args) {
-			return
+		if !isMapMatch(rt.querys, r.URL.Query(), st) {
+			return matchNone
 		}
 	}
 
 	// check headers
 	if len(rt.headers) > 0 {
-		if !isMapMatch(rt.headers, r.Header, args) {
-			return
+		if !isHeaderMatch(rt, r.Header, st) {
+			return matchNone
 		}
 	}
 
-	return true
+	// check user defined predicates
+	if len(rt.matchers) > 0 {
+		if !isMatchersMatch(rt.matchers, r, st) {
+			return matchNone
+		}
+	}
+
+	// check method at last, so that "405 Method Not Allowed" can be detected
+	if len(rt.methods) > 0 {
+		if !isSliceMatch(rt.methods, method, st) {
+			return matchMethod
+		}
+	}
+
+	// check the media types after method, so that "405 Method Not Allowed" is preferred
+	if len(rt.consumes) > 0 {
+		if !isConsumesMatch(rt.consumes, r) {
+			return matchUnsupported
+		}
+	}
+	if len(rt.produces) > 0 {
+		if st.mediaType, st.quality = negotiate(rt.produces, ctx); st.quality == 0 {
+			return matchNotAcceptable
+		}
+	}
+
+	// check scheme at last, so that the redirect to https is only for the acceptable requests
+	if len(rt.schemes) > 0 {
+		if !isSliceMatch(rt.schemes, ctx.scheme, st) {
+			return matchScheme
+		}
+	}
+
+	return matchFull
 }
 
 // isSingleMatch use "==" or regexp to validate a single argument of request is match or not
-func isSingleMatch(item interface{}, single string, args map[string]string) bool {
+func isSingleMatch(item interface{}, single string, st *matchState) bool {
 	switch item.(type) {
 	case string:
 		return single == item.(string)
 
 	case *regexp.Regexp:
-		return item.(*regexp.Regexp).MatchString(single)
+		return st.route.regexp(item.(*regexp.Regexp)).MatchString(single)
 
 	case *namedRegexp:
 		nr := item.(*namedRegexp)
 
-		if !nr.MatchString(single) {
+		if nr.conv != nil {
+			return isTypedMatch(nr, single, st)
+		}
+
+		if !st.route.regexp(nr.Regexp).MatchString(single) {
 			return false
 		}
-		args[nr.Name] = single
+		if nr.Name != "" {
+			st.args[nr.Name] = single
+		}
 		return true
 
+	case *catchAll:
+		if ca := item.(*catchAll); ca.Name != "" {
+			st.args[ca.Name] = single
+		}
+		return true
+
+	case *hostTemplate:
+		ht := item.(*hostTemplate)
+		return isTemplateMatch(ht.Regexp, ht.parts, single, st)
+
+	case *segmentTemplate:
+		tpl := item.(*segmentTemplate)
+		return isTemplateMatch(tpl.Regexp, tpl.parts, single, st)
+
 	default:
 		panic("Unknow type of slice item")
 	}
 }
 
+// isPathsMatch check the request paths match the route paths, the optional segments
+// can be omitted, and the catch-all segment match zero or more remaining segments with the extension
+func isPathsMatch(rtPaths []interface{}, paths []string, ext string, st *matchState) bool {
+	for i, item := range rtPaths {
+		if _, ok := item.(*catchAll); ok {
+			return isSingleMatch(item, strings.Join(restPaths(paths, ext, i), "/"), st)
+		}
+
+		if i >= len(paths) {
+			// the remaining segments are all optional
+			nr, ok := item.(*namedRegexp)
+			return ok && nr.Optional
+		}
+
+		if !isSingleMatch(item, paths[i], st) {
+			return false
+		}
+	}
+
+	return len(rtPaths) == len(paths)
+}
+
+// restPaths return the request paths from i, the extension is re-appended to the last segment
+func restPaths(paths []string, ext string, i int) []string {
+	if ext == "" || i >= len(paths) {
+		return paths[i:]
+	}
+
+	rest := append([]string(nil), paths[i:]...)
+	rest[len(rest)-1] += "." + ext
+	return rest
+}
+
 // isSliceMatch check if one item in the route is the request argument
-func isSliceMatch(rtSlice []interface{}, single string, args map[string]string) bool {
+func isSliceMatch(rtSlice []interface{}, single string, st *matchState) bool {
 	for i := range rtSlice {
-		if isSingleMatch(rtSlice[i], single, args) {
+		if isSingleMatch(rtSlice[i], single, st) {
 			return true
 		}
 	}
@@ -192,7 +579,7 @@ func isSliceMatch(rtSlice []interface{}, single string, args map[string]string)
 }
 
 // isMapMatch check if all map key of route are exists in request map, and at most one item of value(slice) is match the route
-func isMapMatch(rtMap map[string][]interface{}, reqMap map[string][]string, args map[string]string) bool {
+func isMapMatch(rtMap map[string][]interface{}, reqMap map[string][]string, st *matchState) bool {
 loop:
 	for k := range rtMap {
 		slice, ok := reqMap[k]
@@ -201,9 +588,119 @@ loop:
 		}
 
 		for i := range slice {
-			if isSliceMatch(rtMap[k], slice[i], args) {
+			if isSliceMatch(rtMap[k], slice[i], st) {
+				continue loop
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// autoAllowed add the automatically answered methods to the allowed methods
+func autoAllowed(allowed []string) []string {
+	for _, method := range allowed {
+		if method == "GET" {
+			allowed = append(allowed, "HEAD")
+			break
+		}
+	}
+	return append(allowed, "OPTIONS")
+}
+
+// optionsHandler answer OPTIONS, the "Allow" header is already set
+func optionsHandler(ctx *Ctx) error {
+	ctx.W.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// headResponseWriter is a http.ResponseWriter which discard the body
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// standardMethods are probed against the method regexps of routes for the "Allow" header
+var standardMethods = []string{"CONNECT", "DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT", "TRACE"}
+
+// appendMethods append the methods of a route to dst, the literal ones and
+// the standard methods matched by the regexp ones
+func appendMethods(dst []string, rtMethods []interface{}, st *matchState) []string {
+	for _, item := range rtMethods {
+		if s, ok := item.(string); ok {
+			dst = append(dst, s)
+			continue
+		}
+		for _, method := range standardMethods {
+			if isSingleMatch(item, method, st) {
+				dst = append(dst, method)
+			}
+		}
+	}
+	return dst
+}
+
+// uniqueSorted sort a string slice and remove the duplicates
+func uniqueSorted(slice []string) []string {
+	sort.Strings(slice)
+
+	unique := slice[:0]
+	for i := range slice {
+		if i == 0 || slice[i] != slice[i-1] {
+			unique = append(unique, slice[i])
+		}
+	}
+	return unique
+}
+
+// listHeaders are the comma-separated list headers, which are also matched by each token
+var listHeaders = map[string]bool{
+	"Accept":            true,
+	"Accept-Charset":    true,
+	"Accept-Encoding":   true,
+	"Accept-Language":   true,
+	"Cache-Control":     true,
+	"Connection":        true,
+	"Content-Encoding":  true,
+	"If-Match":          true,
+	"If-None-Match":     true,
+	"Pragma":            true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+	"Vary":              true,
+	"Via":               true,
+	"X-Forwarded-For":   true,
+}
+
+// isHeaderMatch check the request headers like isMapMatch, the comma-separated list headers are
+// also matched by each token, and the literal values are compared case-insensitively if configured
+func isHeaderMatch(rt *Route, header http.Header, st *matchState) bool {
+loop:
+	for k, items := range rt.headers {
+		values, ok := header[k]
+		if !ok {
+			return false
+		}
+
+		for _, value := range values {
+			if isHeaderValueMatch(items, value, rt.headerFold, st) {
 				continue loop
 			}
+			if !listHeaders[k] {
+				continue
+			}
+			for _, token := range strings.Split(value, ",") {
+				if isHeaderValueMatch(items, strings.TrimSpace(token), rt.headerFold, st) {
+					continue loop
+				}
+			}
 		}
 
 		return false
@@ -212,6 +709,21 @@ loop:
 	return true
 }
 
+func isHeaderValueMatch(items []interface{}, value string, fold bool, st *matchState) bool {
+	for _, item := range items {
+		if s, ok := item.(string); ok && fold {
+			if strings.EqualFold(s, value) {
+				return true
+			}
+			continue
+		}
+		if isSingleMatch(item, value, st) {
+			return true
+		}
+	}
+	return false
+}
+
 func getPathsAndExt(u *url.URL) (paths []string, ext string) {
 	paths0 := strings.Split(path.Clean(u.Path), "/")
 
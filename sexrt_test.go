@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	gourl "net/url"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -22,7 +24,7 @@ var (
 		return errHehe
 	}
 
-	testErrorHandler = func(err error) {}
+	testErrorHandler = func(ctx *Ctx, err error) {}
 
 	testHandler = func(ctx *Ctx) error {
 		io.WriteString(ctx.W, testContent)
@@ -49,7 +51,7 @@ func TestMuxNew(t *testing.T) {
 	})
 
 	mux.HandleNotFound(testNotFoundHandler)
-	mux.HandleError(func(err error) {
+	mux.HandleError(func(ctx *Ctx, err error) {
 		if err != errHehe {
 			t.Fatal("user defined error handler not correct!")
 		}
@@ -117,6 +119,53 @@ func TestMuxRoutePath(t *testing.T) {
 	}
 }
 
+func TestMuxRouteWildcard(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	argsHandler := func(ctx *Ctx) error {
+		_, err := io.WriteString(ctx.W, fmt.Sprint(ctx.Args))
+		return err
+	}
+	mux.NewRoute().Path("files", `{rest:*}`).Func(argsHandler)
+	mux.NewRoute().Path("list", `{page?:^\d+$}`, `{size?:^\d+$}`).Func(argsHandler)
+
+	tests := []struct {
+		u    string
+		body string
+	}{
+		{"/files", "map[rest:]"},
+		{"/files/a", "map[rest:a]"},
+		{"/files/a/b.tar.gz", "map[rest:a/b.tar.gz]"},
+		{"/list", "map[]"},
+		{"/list/2", "map[page:2]"},
+		{"/list/2/10", "map[page:2 size:10]"},
+	}
+	for _, test := range tests {
+		testHTTPResponse("GET", srv.URL+test.u, "", func(body string, resp *http.Response) {
+			if body != test.body {
+				t.Fatalf("%s: body %q not equal %q", test.u, body, test.body)
+			}
+		})
+	}
+
+	us := []string{
+		srv.URL + "/list/x",
+		srv.URL + "/list/2/x",
+		srv.URL + "/list/2/10/1",
+		srv.URL + "/file/a",
+	}
+	for _, u := range us {
+		testHTTPResponse("GET", u, "", func(body string, resp *http.Response) {
+			if resp.StatusCode != 404 {
+				t.Fatal(u + ": can found?!")
+			}
+		})
+	}
+}
+
 func TestMuxRouteExt(t *testing.T) {
 	mux := NewMux()
 	rt := mux.NewRoute()
@@ -180,12 +229,136 @@ func TestMuxRouteMethod(t *testing.T) {
 		}
 	})
 	testHTTPResponse("GET", u, "", func(body string, resp *http.Response) {
+		if resp.StatusCode != 405 {
+			t.Fatal(u + ": method allowed?!")
+		}
+	})
+
+	mux.NewRoute().Path("user").Put().Func(testHandler)
+	mux.NewRoute().Path("user").Method("DELETE", "PUT").Func(testHandler)
+	mux.NewRoute().Path("user").Method(`{^PATCH$}`).Func(testHandler)
+	mux.NewRoute().Path("user").Get().Header("X-Test", "test").Func(testHandler)
+
+	u = srv.URL + "/user"
+	testHTTPResponse("POST", u, "", func(body string, resp *http.Response) {
+		if resp.StatusCode != 405 {
+			t.Fatal(u + ": method allowed?!")
+		}
+		if allow := resp.Header.Get("Allow"); allow != "DELETE, PATCH, PUT" {
+			t.Fatal(u+": Allow header not correct:", allow)
+		}
+	})
+
+	mux.HandleMethodNotAllowed(func(ctx *Ctx) error {
+		_, err := io.WriteString(ctx.W, "not allowed:"+ctx.W.Header().Get("Allow"))
+		return err
+	})
+	testHTTPResponse("POST", u, "", func(body string, resp *http.Response) {
+		if body != "not allowed:DELETE, PATCH, PUT" {
+			t.Fatal("user defined method not allowed handler not correct!")
+		}
+	})
+
+	u = srv.URL + "/none"
+	testHTTPResponse("POST", u, "", func(body string, resp *http.Response) {
 		if resp.StatusCode != 404 {
 			t.Fatal(u + ": can found?!")
 		}
 	})
 }
 
+func TestMuxRouteHeaderCanonical(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.NewRoute().Path("lower").Header("accept", "text/html").Func(testHandler)
+	mux.NewRoute().Path("fold").Header("X-Mode", "Debug", "cache-control", `{^no-(cache|store)$}`).
+		IgnoreHeaderCase().Func(testHandler)
+	mux.NewRoute().Path("case").Header("X-Mode", "Debug").Func(testHandler)
+
+	tests := []struct {
+		u      string
+		header map[string]string
+		status int
+	}{
+		{"/lower", map[string]string{"Accept": "text/html"}, 200},
+		{"/lower", map[string]string{"Accept": "application/json, text/html;q=0.9"}, 404},
+		{"/lower", map[string]string{"Accept": "application/json,text/html"}, 200},
+		{"/fold", map[string]string{"X-Mode": "DEBUG", "Cache-Control": "max-age=0, No-Store"}, 200},
+		{"/fold", map[string]string{"X-Mode": "debug", "Cache-Control": "max-age=0"}, 404},
+		{"/case", map[string]string{"X-Mode": "Debug"}, 200},
+		{"/case", map[string]string{"X-Mode": "debug"}, 404},
+		// X-Mode is not a list header
+		{"/case", map[string]string{"X-Mode": "Debug, Trace"}, 404},
+	}
+	for _, test := range tests {
+		testHTTPResponseSetHeader("GET", srv.URL+test.u, "", test.header, func(body string, resp *http.Response) {
+			if resp.StatusCode != test.status {
+				t.Fatalf("%s %v: status %d not equal %d", test.u, test.header, resp.StatusCode, test.status)
+			}
+		})
+	}
+}
+
+func TestMuxAutoMethods(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.NewRoute().Path("user").Get().Func(func(ctx *Ctx) error {
+		ctx.W.Header().Set("X-Test", "get")
+		_, err := io.WriteString(ctx.W, testContent)
+		return err
+	})
+	mux.NewRoute().Path("user").Post().Func(testHandler)
+
+	u := srv.URL + "/user"
+	testHTTPResponse("HEAD", u, "", func(body string, resp *http.Response) {
+		if resp.StatusCode != 405 {
+			t.Fatal(u + ": HEAD answered without auto methods!")
+		}
+	})
+
+	mux.AutoMethods(true)
+	testHTTPResponse("HEAD", u, "", func(body string, resp *http.Response) {
+		if resp.StatusCode != 200 || resp.Header.Get("X-Test") != "get" || body != "" {
+			t.Fatal(u + ": HEAD not served by GET route!")
+		}
+	})
+	testHTTPResponse("OPTIONS", u, "", func(body string, resp *http.Response) {
+		if resp.StatusCode != 200 {
+			t.Fatal(u + ": OPTIONS not answered!")
+		}
+		if allow := resp.Header.Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+			t.Fatal(u+": Allow header not correct:", allow)
+		}
+	})
+	testHTTPResponse("PUT", u, "", func(body string, resp *http.Response) {
+		if allow := resp.Header.Get("Allow"); resp.StatusCode != 405 || allow != "GET, HEAD, OPTIONS, POST" {
+			t.Fatal(u+": Allow header not correct:", allow)
+		}
+	})
+
+	u = srv.URL + "/none"
+	testHTTPResponse("OPTIONS", u, "", func(body string, resp *http.Response) {
+		if resp.StatusCode != 404 {
+			t.Fatal(u + ": can found?!")
+		}
+	})
+
+	// the explicitly registered route wins
+	mux.NewRoute().Path("user").Method("OPTIONS").Func(testHandler)
+	u = srv.URL + "/user"
+	testHTTPResponse("OPTIONS", u, "", func(body string, resp *http.Response) {
+		if body != testContent {
+			t.Fatal(u + ": body not correct!")
+		}
+	})
+}
+
 func TestMuxRouteHost(t *testing.T) {
 	mux := NewMux()
 	rt := mux.NewRoute()
@@ -278,6 +451,202 @@ func TestMuxRouteHeader(t *testing.T) {
 	})
 }
 
+func TestMuxRoutePriority(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// registration order
+	mux.NewRoute().Path("user", `{name:\w+}`).Func(testWriteHandler("first"))
+	mux.NewRoute().Path("user", "new").Func(testWriteHandler("second"))
+
+	for i := 0; i < 10; i++ {
+		testHTTPResponse("GET", srv.URL+"/user/new", "", func(body string, resp *http.Response) {
+			if body != "first" {
+				t.Fatal("registration order not respected:", body)
+			}
+		})
+	}
+
+	// most specific wins
+	mux.MostSpecificWins(true)
+	testHTTPResponse("GET", srv.URL+"/user/new", "", func(body string, resp *http.Response) {
+		if body != "second" {
+			t.Fatal("literal segment not beat regexp:", body)
+		}
+	})
+
+	mux.NewRoute().Path("user", "new").Method("GET").Func(testWriteHandler("third"))
+	testHTTPResponse("GET", srv.URL+"/user/new", "", func(body string, resp *http.Response) {
+		if body != "third" {
+			t.Fatal("more constraints not beat fewer:", body)
+		}
+	})
+	testHTTPResponse("POST", srv.URL+"/user/new", "", func(body string, resp *http.Response) {
+		if body != "second" {
+			t.Fatal("POST not fall back:", body)
+		}
+	})
+
+	// explicit priority
+	mux.NewRoute().Path("user", `{name:\w+}`).Priority(1).Func(testWriteHandler("fourth"))
+	testHTTPResponse("GET", srv.URL+"/user/new", "", func(body string, resp *http.Response) {
+		if body != "fourth" {
+			t.Fatal("priority not respected:", body)
+		}
+	})
+
+	mux.MostSpecificWins(false)
+	mux.NewRoute().Path("user", "new").Priority(1).Func(testWriteHandler("fifth"))
+	testHTTPResponse("GET", srv.URL+"/user/new", "", func(body string, resp *http.Response) {
+		if body != "fourth" {
+			t.Fatal("registration order not respected in the same priority:", body)
+		}
+	})
+}
+
+func TestMuxRouteArgsNotLeak(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// captures id and name, then fails on the extension
+	mux.NewRoute().Path(`{id:^[a-z]+$}`, `{name:^[a-z]+$}`).Ext("json").Func(testWriteHandler("first"))
+	// captures name only
+	mux.NewRoute().Path(`{^\w+$}`, `{name:^\w+$}`).Func(func(ctx *Ctx) error {
+		_, err := io.WriteString(ctx.W, fmt.Sprint(ctx.Args))
+		return err
+	})
+
+	testHTTPResponse("GET", srv.URL+"/foo/bar", "", func(body string, resp *http.Response) {
+		if body != "map[name:bar]" {
+			t.Fatal("args of failed route leaked:", body)
+		}
+	})
+	testHTTPResponse("GET", srv.URL+"/foo/bar.json", "", func(body string, resp *http.Response) {
+		if body != "first" {
+			t.Fatal("/foo/bar.json: body not correct!", body)
+		}
+	})
+}
+
+func TestMuxRemoveReplace(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.NewRoute().Name("user").Path("user").Use(testMiddleware("mw")).Func(testWriteHandler("user"))
+	mux.NewRoute().Path("user").Func(testWriteHandler("fallback"))
+
+	if !mux.Replace("user", testWriteHandler("replaced")) || mux.Replace("none", testHandler) || mux.Replace("", testHandler) {
+		t.Fatal("replace result not correct")
+	}
+	testHTTPResponse("GET", srv.URL+"/user", "", func(body string, resp *http.Response) {
+		if body != "mw(replaced)" {
+			t.Fatal("/user: handler not replaced:", body)
+		}
+	})
+
+	if !mux.Remove("user") || mux.Remove("user") || mux.Remove("") {
+		t.Fatal("remove result not correct")
+	}
+	testHTTPResponse("GET", srv.URL+"/user", "", func(body string, resp *http.Response) {
+		if body != "fallback" {
+			t.Fatal("/user: route not removed:", body)
+		}
+	})
+	if _, err := mux.URL("user", nil); err == nil {
+		t.Fatal("removed route can be built")
+	}
+	if len(mux.Routes()) != 1 {
+		t.Fatal("unnamed route removed")
+	}
+}
+
+func TestMuxConcurrentRegister(t *testing.T) {
+	mux := NewMux()
+	mux.NewRoute().Name("index").Path("index").Func(testHandler)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	// live load
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest("GET", "/index", nil))
+				if w.Code != 200 {
+					t.Error("/index: status not correct:", w.Code)
+					return
+				}
+				mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", fmt.Sprintf("/plugin/%d", j%100), nil))
+				mux.Routes()
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("plugin%d", i)
+		mux.NewRoute().Name(name).Path("plugin", fmt.Sprint(i)).Func(testHandler)
+		if _, err := mux.URL(name, nil); err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 0 {
+			mux.Remove(name)
+		} else {
+			mux.Replace(name, testWriteHandler(name))
+		}
+		if i == 50 {
+			mux.MostSpecificWins(true)
+			mux.AutoMethods(true)
+			mux.TrustProxies("10.0.0.0/8")
+			mux.TrustForwardedHost(true)
+			mux.RedirectToHTTPS(true)
+			mux.SetLogger(log.New(ioutil.Discard, "", 0))
+		}
+
+		// the settings read while serving
+		mux.Use(func(next func(*Ctx) error) func(*Ctx) error {
+			return next
+		})
+		mux.HandleNotFound(func(ctx *Ctx) error {
+			http.NotFound(ctx.W, ctx.R)
+			return nil
+		})
+	}
+
+	close(stop)
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/plugin/99", nil))
+	if w.Body.String() != "plugin99" {
+		t.Fatal("/plugin/99: body not correct:", w.Body.String())
+	}
+	if len(mux.Routes()) != 51 {
+		t.Fatal("len of routes isn't 51")
+	}
+}
+
+func testWriteHandler(s string) routeHandler {
+	return func(ctx *Ctx) error {
+		_, err := io.WriteString(ctx.W, s)
+		return err
+	}
+}
+
 func checkPointerEqual(p, p0 interface{}) bool {
 	return fmt.Sprintf("%p", p0) == fmt.Sprintf("%p", p)
 }
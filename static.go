@@ -0,0 +1,82 @@
+package sexrt
+
+import (
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Static register a static file server with the building route, the paths of the route is the prefix,
+// and the remaining segments are the file name. The Content-Type is set by the extension,
+// and If-Modified-Since and ETag are supported. The directories serve the first existing index file
+// (e.g. "index.html") if given, otherwise the not found handler is called, as for the missing files.
+func (rt *Route) Static(fsys http.FileSystem, index ...string) {
+	prefixLen := len(rt.paths)
+	mux := rt.mux
+
+	rt.clone().Path(`{*}`).Func(func(ctx *Ctx) error {
+		name, ok := staticName(restPaths(ctx.paths, ctx.ext, prefixLen))
+		if !ok {
+			return Error(http.StatusBadRequest, "invalid path")
+		}
+
+		if served, err := serveFile(ctx, fsys, name, index); served || err != nil {
+			return err
+		}
+
+		ctx.Route = nil
+		return mux.findNotFound(ctx)(ctx)
+	})
+}
+
+// StaticFS is same as Static, but serve files from a fs.FS
+func (rt *Route) StaticFS(fsys fs.FS, index ...string) {
+	rt.Static(http.FS(fsys), index...)
+}
+
+// staticName join the segments to the file name, reject the traversal
+func staticName(segments []string) (string, bool) {
+	for _, segment := range segments {
+		if segment == ".." || strings.ContainsAny(segment, "\\\x00") {
+			return "", false
+		}
+	}
+
+	return "/" + strings.Join(segments, "/"), true
+}
+
+// serveFile serve a file or the index file of a directory, and report whether it is served
+func serveFile(ctx *Ctx, fsys http.FileSystem, name string, index []string) (bool, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false, nil
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if stat.IsDir() {
+		for _, indexName := range index {
+			if served, err := serveFile(ctx, fsys, path.Join(name, indexName), nil); served || err != nil {
+				return served, err
+			}
+		}
+		return false, nil
+	}
+
+	w := ctx.W
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("ETag", fmt.Sprintf(`W/"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()))
+
+	// handle If-Modified-Since, If-None-Match and Range
+	http.ServeContent(w, ctx.R, stat.Name(), stat.ModTime(), f)
+	return true, nil
+}
@@ -0,0 +1,95 @@
+package sexrt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRouteStatic(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	modTime := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"css/main.css":    {Data: []byte("body {}"), ModTime: modTime},
+		"docs/index.html": {Data: []byte("<p>docs</p>"), ModTime: modTime},
+		"LICENSE":         {Data: []byte("MIT"), ModTime: modTime},
+	}
+
+	mux.HandleNotFound(testWriteHandler("notfound"))
+	mux.NewRoute().Path("static").StaticFS(fsys, "index.html")
+	mux.NewRoute().Path("noindex").StaticFS(fsys)
+
+	tests := []struct {
+		u           string
+		body        string
+		contentType string
+	}{
+		{"/static/css/main.css", "body {}", "text/css"},
+		{"/static/docs", "<p>docs</p>", "text/html"},
+		{"/static/docs/", "<p>docs</p>", "text/html"},
+		{"/static/LICENSE", "MIT", "text/plain"},
+		{"/static/css/none.css", "notfound", "text/plain"},
+		{"/static/css", "notfound", "text/plain"},
+		{"/noindex/docs", "notfound", "text/plain"},
+	}
+	for _, test := range tests {
+		testHTTPResponse("GET", srv.URL+test.u, "", func(body string, resp *http.Response) {
+			if body != test.body {
+				t.Fatalf("%s: body %q not equal %q", test.u, body, test.body)
+			}
+			// the system mime types may add parameters
+			if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, test.contentType) {
+				t.Fatalf("%s: Content-Type %q not equal %q", test.u, contentType, test.contentType)
+			}
+		})
+	}
+
+	var etag string
+	u := srv.URL + "/static/css/main.css"
+	testHTTPResponse("GET", u, "", func(body string, resp *http.Response) {
+		etag = resp.Header.Get("ETag")
+		if etag == "" || resp.Header.Get("Last-Modified") != modTime.Format(http.TimeFormat) {
+			t.Fatal(u + ": cache headers not set")
+		}
+	})
+
+	headers := []map[string]string{
+		{"If-None-Match": etag},
+		{"If-Modified-Since": modTime.Format(http.TimeFormat)},
+	}
+	for _, header := range headers {
+		testHTTPResponseSetHeader("GET", u, "", header, func(body string, resp *http.Response) {
+			if resp.StatusCode != 304 {
+				t.Fatalf("%s: %v not modified, but got %d", u, header, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestStaticName(t *testing.T) {
+	tests := []struct {
+		segments []string
+		ext      string
+		name     string
+		ok       bool
+	}{
+		{[]string{"css", "main"}, "css", "/css/main.css", true},
+		{[]string{"LICENSE"}, "", "/LICENSE", true},
+		{nil, "html", "/", true},
+		{[]string{"..", "secret"}, "", "", false},
+		{[]string{`a\..\b`}, "", "", false},
+	}
+	for _, test := range tests {
+		name, ok := staticName(restPaths(test.segments, test.ext, 0))
+		if name != test.name || ok != test.ok {
+			t.Fatalf("%v %q: %q %v not equal %q %v", test.segments, test.ext, name, ok, test.name, test.ok)
+		}
+	}
+}
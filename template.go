@@ -0,0 +1,194 @@
+package sexrt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// segmentTemplate is a path segment mixing the literal text and the captures
+// (e.g. "user-{id:\d+}", "v{major:\d+}.{minor:\d+}", "{name}@{domain}"),
+// it is compiled to one regexp with named subexpressions
+type segmentTemplate struct {
+	Pattern string
+	*regexp.Regexp
+	parts []interface{} // the literal strings and the captures in order, used for url building
+}
+
+// identRegexp match the capture without regexp (e.g. "{name}")
+var identRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// isTemplate check a pattern mix the literal text and the captures
+func isTemplate(s string) bool {
+	return strings.Contains(s, "{") && !isWholeBraces(s)
+}
+
+// parseTemplate split a template into the literal strings and the captures, the capture without
+// regexp (e.g. "{name}") use the defaultPattern, and return the regexp source without anchors
+func parseTemplate(s, defaultPattern string) (source string, parts []interface{}, err error) {
+	var buf strings.Builder
+	names := make(map[string]bool)
+
+	for i := 0; i < len(s); {
+		if s[i] != '{' {
+			j := strings.IndexByte(s[i:], '{')
+			if j < 0 {
+				j = len(s) - i
+			}
+			literal := s[i : i+j]
+			parts = append(parts, literal)
+			buf.WriteString(regexp.QuoteMeta(literal))
+			i += j
+			continue
+		}
+
+		// find the closing "}"
+		depth, j := 0, i
+		for ; j < len(s); j++ {
+			if s[j] == '{' {
+				depth++
+			} else if s[j] == '}' {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		if j == len(s) {
+			return "", nil, errors.New(`unclosed "{"`)
+		}
+
+		pattern := s[i : j+1]
+		if identRegexp.MatchString(pattern[1 : len(pattern)-1]) {
+			pattern = "{" + pattern[1:len(pattern)-1] + ":" + defaultPattern + "}"
+		}
+
+		item, err := parseString(pattern)
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch item := item.(type) {
+		case *regexp.Regexp:
+			buf.WriteString("(?:" + trimAnchors(item.String()) + ")")
+
+		case *namedRegexp:
+			if item.Optional {
+				return "", nil, errors.New("optional argument isn't supported in template")
+			}
+			if names[item.Name] {
+				return "", nil, fmt.Errorf("duplicate argument %q in template", item.Name)
+			}
+			names[item.Name] = true
+			buf.WriteString("(?P<" + item.Name + ">" + trimAnchors(item.String()) + ")")
+
+		default:
+			return "", nil, errors.New("catch-all isn't supported in template")
+		}
+		parts = append(parts, item)
+
+		i = j + 1
+	}
+
+	return buf.String(), parts, nil
+}
+
+// compileSegmentTemplate compile a path segment template
+func compileSegmentTemplate(s string) (*segmentTemplate, error) {
+	source, parts, err := parseTemplate(s, `.+?`)
+	if err != nil {
+		return nil, err
+	}
+
+	reg, err := regexp.Compile("^" + source + "$")
+	if err != nil {
+		// e.g. invalid capture name
+		return nil, err
+	}
+
+	return &segmentTemplate{Pattern: s, Regexp: reg, parts: parts}, nil
+}
+
+// trimAnchors remove the "^" and "$" of a regexp, so that it can be embedded into another one
+func trimAnchors(pattern string) string {
+	pattern = strings.TrimPrefix(pattern, "^")
+	if strings.HasSuffix(pattern, "$") && !strings.HasSuffix(pattern, `\$`) {
+		pattern = pattern[:len(pattern)-1]
+	}
+	return pattern
+}
+
+// isTemplateMatch match a template regexp and stage the named captures of parts
+func isTemplateMatch(reg *regexp.Regexp, parts []interface{}, single string, st *matchState) bool {
+	m := reg.FindStringSubmatch(single)
+	if m == nil {
+		return false
+	}
+
+	for _, part := range parts {
+		nr, ok := part.(*namedRegexp)
+		if !ok {
+			continue
+		}
+
+		value := m[reg.SubexpIndex(nr.Name)]
+		if nr.conv != nil {
+			if !isTypedMatch(nr, value, st) {
+				return false
+			}
+			continue
+		}
+		st.args[nr.Name] = value
+	}
+	return true
+}
+
+// isTemplateExtMatch match the paths with the extension joined to the last segment, so that the dot
+// of the last segment template (e.g. "v{major:\d+}.{minor:\d+}") isn't taken as the extension
+func isTemplateExtMatch(rtPaths []interface{}, paths []string, ext string, st *matchState) bool {
+	if ext == "" || len(paths) == 0 || len(rtPaths) != len(paths) {
+		return false
+	}
+	if _, ok := rtPaths[len(rtPaths)-1].(*segmentTemplate); !ok {
+		return false
+	}
+
+	joined := append(append([]string(nil), paths[:len(paths)-1]...), paths[len(paths)-1]+"."+ext)
+	return isPathsMatch(rtPaths, joined, "", st)
+}
+
+// buildTemplate build a template by substituting the captures with args
+func (rt *Route) buildTemplate(reg *regexp.Regexp, parts []interface{}, args map[string]string) (string, error) {
+	var buf strings.Builder
+
+	for _, part := range parts {
+		switch part := part.(type) {
+		case string:
+			buf.WriteString(part)
+
+		case *regexp.Regexp:
+			return "", fmt.Errorf("unnamed regexp %q can't be built", part.String())
+
+		case *namedRegexp:
+			value, err := rt.buildSingle(part, args)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(value)
+		}
+	}
+
+	// the captures may match the literal text of the others, so check the built value is parsed back
+	s := buf.String()
+	m := reg.FindStringSubmatch(s)
+	if m == nil {
+		return "", fmt.Errorf("built value %q doesn't match %q", s, reg.String())
+	}
+	for _, part := range parts {
+		if nr, ok := part.(*namedRegexp); ok && m[reg.SubexpIndex(nr.Name)] != args[nr.Name] {
+			return "", fmt.Errorf("built value %q is ambiguous for argument %q", s, nr.Name)
+		}
+	}
+	return s, nil
+}
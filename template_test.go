@@ -0,0 +1,100 @@
+package sexrt
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteSegmentTemplate(t *testing.T) {
+	mux := NewMux()
+
+	writeArgs := func(names ...string) routeHandler {
+		return func(ctx *Ctx) error {
+			var values []string
+			for _, name := range names {
+				values = append(values, ctx.Args[name])
+			}
+			_, err := ctx.W.Write([]byte(strings.Join(values, ",")))
+			return err
+		}
+	}
+	mux.NewRoute().Name("user").Path("user-{id:\\d+}").Func(writeArgs("id"))
+	mux.NewRoute().Name("version").Path("api", "v{major:\\d+}.{minor:\\d+}").Func(writeArgs("major", "minor"))
+	mux.NewRoute().Name("mail").Path("{name}@{domain}").Ext("json").Func(writeArgs("name", "domain"))
+	mux.NewRoute().Path("{name}@{domain}").Func(writeArgs("name", "domain"))
+	mux.NewRoute().Name("page").Path("page-{n:int}").Func(func(ctx *Ctx) error {
+		_, err := ctx.W.Write([]byte(ctx.Args["n"]))
+		if ctx.Int("n") != 3 {
+			t.Error("typed argument not converted")
+		}
+		return err
+	})
+
+	tests := []struct {
+		u      string
+		status int
+		body   string
+	}{
+		{"/user-12", 200, "12"},
+		{"/user-x", 404, ""},
+		{"/xuser-12", 404, ""},
+		{"/api/v1.2", 200, "1,2"},
+		{"/api/v1.x", 404, ""},
+		{"/api/v1", 404, ""},
+		{"/jm@example.com.json", 200, "jm,example.com"},
+		{"/jm.json", 404, ""},
+		{"/jm@example.com", 200, "jm,example.com"},
+		{"/jm@example", 200, "jm,example"},
+		{"/page-3", 200, "3"},
+		{"/page-x", 404, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.u, nil))
+		if w.Code != test.status || (test.status == 200 && w.Body.String() != test.body) {
+			t.Fatalf("%s: response %d %q not correct", test.u, w.Code, w.Body.String())
+		}
+	}
+
+	urls := []struct {
+		name string
+		args map[string]string
+		url  string
+	}{
+		{"user", map[string]string{"id": "12"}, "/user-12"},
+		{"version", map[string]string{"major": "1", "minor": "2"}, "/api/v1.2"},
+		{"mail", map[string]string{"name": "jm", "domain": "example.com"}, "/jm@example.com.json"},
+	}
+	for _, test := range urls {
+		u, err := mux.URL(test.name, test.args)
+		if err != nil || u != test.url {
+			t.Fatalf("%s: url %q not equal %q: %v", test.name, u, test.url, err)
+		}
+	}
+
+	errArgs := []struct {
+		name string
+		args map[string]string
+	}{
+		{"user", map[string]string{"id": "x"}},
+		{"version", map[string]string{"major": "1"}},
+		// parsed back as name "a", domain "b@c"
+		{"mail", map[string]string{"name": "a@b", "domain": "c"}},
+		{"page", map[string]string{"n": "x"}},
+	}
+	for _, test := range errArgs {
+		if u, err := mux.URL(test.name, test.args); err == nil {
+			t.Fatalf("%s: %v built to %q without error", test.name, test.args, u)
+		}
+	}
+
+	if s := mux.Routes()[1].String(); s != `/api/v{major:\d+}.{minor:\d+}` {
+		t.Fatal("segment template not introspected:", s)
+	}
+
+	if new(Route).Path("user-{id?:\\d+}").Err() == nil || new(Route).Path("a{b").Err() == nil ||
+		new(Route).Path("{a}-{a}").Err() == nil || new(Route).Host("{a}.{a:[a-z]+}.com").Err() == nil {
+		t.Fatal("invalid template without error")
+	}
+}
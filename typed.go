@@ -0,0 +1,134 @@
+package sexrt
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// converter validate and convert a typed argument (e.g. "{id:int}")
+type converter struct {
+	Name   string
+	Regexp *regexp.Regexp
+	parse  func(string) (interface{}, error)
+}
+
+// converters are the built-in typed argument converters
+var converters = map[string]*converter{
+	"int": {
+		Name:   "int",
+		Regexp: regexp.MustCompile(`^[+-]?\d+$`),
+		parse: func(s string) (interface{}, error) {
+			return strconv.Atoi(s)
+		},
+	},
+	"uuid": {
+		Name:   "uuid",
+		Regexp: regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+		parse: func(s string) (interface{}, error) {
+			return parseUUID(s)
+		},
+	},
+	"date": {
+		Name:   "date",
+		Regexp: regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`),
+		parse: func(s string) (interface{}, error) {
+			return time.Parse("2006-01-02", s)
+		},
+	},
+	"slug": {
+		Name:   "slug",
+		Regexp: regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`),
+		parse: func(s string) (interface{}, error) {
+			return s, nil
+		},
+	},
+}
+
+// convert validate the argument by regexp and convert it
+func (conv *converter) convert(s string) (interface{}, error) {
+	if !conv.Regexp.MatchString(s) {
+		return nil, fmt.Errorf("invalid %s %q", conv.Name, s)
+	}
+	value, err := conv.parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", conv.Name, s, err)
+	}
+	return value, nil
+}
+
+// RejectInvalidArgs will make a building route respond "400 Bad Request" when a typed argument
+// fails to convert, instead of treating it as a mismatch, the typed segments match any value
+func (rt *Route) RejectInvalidArgs() *Route {
+	rt.rejectInvalidArgs = true
+	return rt
+}
+
+// isTypedMatch validate and convert a typed argument, the failure is a mismatch,
+// or it is staged as an error if the route rejects invalid arguments
+func isTypedMatch(nr *namedRegexp, single string, st *matchState) bool {
+	value, err := nr.conv.convert(single)
+	if err != nil {
+		if !st.strict {
+			return false
+		}
+		if st.err == nil {
+			st.err = fmt.Errorf("argument %q: %w", nr.Name, err)
+		}
+	}
+
+	if nr.Name != "" {
+		st.args[nr.Name] = single
+		if err == nil {
+			st.values[nr.Name] = value
+		}
+	}
+	return true
+}
+
+// badRequestHandler return the conversion failure as "400 Bad Request"
+func badRequestHandler(err error) routeHandler {
+	return func(ctx *Ctx) error {
+		return WrapError(http.StatusBadRequest, err.Error(), err)
+	}
+}
+
+// Int return the converted value of a "{name:int}" argument, or 0 if not exists
+func (ctx *Ctx) Int(name string) int {
+	i, _ := ctx.values[name].(int)
+	return i
+}
+
+// UUID return the converted value of a "{name:uuid}" argument, or the zero UUID if not exists
+func (ctx *Ctx) UUID(name string) UUID {
+	u, _ := ctx.values[name].(UUID)
+	return u
+}
+
+// Time return the converted value of a "{name:date}" argument, or the zero Time if not exists
+func (ctx *Ctx) Time(name string) time.Time {
+	t, _ := ctx.values[name].(time.Time)
+	return t
+}
+
+// UUID is the converted value of a "{name:uuid}" argument
+type UUID [16]byte
+
+// String return the canonical form of UUID (e.g. "123e4567-e89b-12d3-a456-426614174000")
+func (u UUID) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+func parseUUID(s string) (u UUID, err error) {
+	b := []byte(strings.Replace(s, "-", "", -1))
+	if len(b) != hex.EncodedLen(len(u)) {
+		return u, fmt.Errorf("invalid length %d", len(b))
+	}
+	_, err = hex.Decode(u[:], b)
+	return
+}
@@ -0,0 +1,95 @@
+package sexrt
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMuxTypedArgs(t *testing.T) {
+	mux := NewMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.NewRoute().Path("user", `{id:int}`).Func(func(ctx *Ctx) error {
+		_, err := fmt.Fprintf(ctx.W, "%d:%s", ctx.Int("id")+1, ctx.Args["id"])
+		return err
+	})
+	mux.NewRoute().Path("order", `{uuid:uuid}`).Func(func(ctx *Ctx) error {
+		_, err := fmt.Fprint(ctx.W, ctx.UUID("uuid"))
+		return err
+	})
+	mux.NewRoute().Path("log", `{when:date}`).Func(func(ctx *Ctx) error {
+		_, err := fmt.Fprint(ctx.W, ctx.Time("when").Weekday())
+		return err
+	})
+	mux.NewRoute().Path("post", `{slug:slug}`).Func(func(ctx *Ctx) error {
+		_, err := fmt.Fprint(ctx.W, ctx.Args["slug"])
+		return err
+	})
+	mux.NewRoute().Path("strict", `{id:int}`).RejectInvalidArgs().Func(testHandler)
+
+	tests := []struct {
+		u    string
+		body string
+	}{
+		{"/user/41", "42:41"},
+		{"/user/-1", "0:-1"},
+		{"/order/123E4567-E89B-12D3-A456-426614174000", "123e4567-e89b-12d3-a456-426614174000"},
+		{"/log/2016-02-29", "Monday"},
+		{"/post/hello-world-2", "hello-world-2"},
+		{"/strict/1", testContent},
+	}
+	for _, test := range tests {
+		testHTTPResponse("GET", srv.URL+test.u, "", func(body string, resp *http.Response) {
+			if body != test.body {
+				t.Fatalf("%s: body %q not equal %q", test.u, body, test.body)
+			}
+		})
+	}
+
+	// conversion failures are mismatches by default
+	us := []string{
+		srv.URL + "/user/abc",
+		srv.URL + "/user/99999999999999999999999",
+		srv.URL + "/order/123e4567-e89b-12d3-a456",
+		srv.URL + "/log/2015-02-29",
+		srv.URL + "/post/Hello--world",
+	}
+	for _, u := range us {
+		testHTTPResponse("GET", u, "", func(body string, resp *http.Response) {
+			if resp.StatusCode != 404 {
+				t.Fatal(u + ": can found?!")
+			}
+		})
+	}
+
+	// or 400 if the route rejects invalid arguments
+	us = []string{
+		srv.URL + "/strict/abc",
+		srv.URL + "/strict/99999999999999999999999",
+	}
+	for _, u := range us {
+		testHTTPResponse("GET", u, "", func(body string, resp *http.Response) {
+			if resp.StatusCode != 400 {
+				t.Fatal(u + ": not bad request!")
+			}
+		})
+	}
+}
+
+func TestParseAppendTyped(t *testing.T) {
+	result := new(Route).parseAppend("Path", nil, `{id:int}`, `{page?:int}`)
+	nr0, ok0 := result[0].(*namedRegexp)
+	nr1, ok1 := result[1].(*namedRegexp)
+	if !ok0 || !ok1 || nr0.Name != "id" || nr0.conv != converters["int"] ||
+		nr1.Name != "page" || !nr1.Optional || nr1.conv != converters["int"] {
+		t.Fatal("typed argument not parsed")
+	}
+
+	if nr := cloneRouteSingle(nr0).(*namedRegexp); nr.conv != nr0.conv {
+		t.Fatal("converter not cloned")
+	}
+}
@@ -0,0 +1,128 @@
+package sexrt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	errMissingValue  = errors.New("missing value of the pair")
+	errDuplicateName = errors.New("duplicate route name")
+)
+
+// RouteError is an error of building a route, such as an invalid regexp or a missing pair value
+type RouteError struct {
+	Route    string // name of the route, empty if unnamed
+	Field    string // the builder method (e.g. "Path", "Query", "Host")
+	Pattern  string // the invalid pattern text
+	Position int    // the position of the pattern in the arguments of builder method
+	Err      error
+}
+
+func (e *RouteError) Error() string {
+	s := "sexrt: "
+	if e.Route != "" {
+		s += fmt.Sprintf("route %q: ", e.Route)
+	}
+	return s + fmt.Sprintf("%s argument %d %q: %v", e.Field, e.Position, e.Pattern, e.Err)
+}
+
+// Unwrap return the cause
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// RouteErrors is a list of RouteError
+type RouteErrors []*RouteError
+
+func (errs RouteErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap return the errors, so that errors.As can find a RouteError
+func (errs RouteErrors) Unwrap() []error {
+	unwrapped := make([]error, 0, len(errs))
+	for _, err := range errs {
+		unwrapped = append(unwrapped, err)
+	}
+	return unwrapped
+}
+
+// Err return the errors recorded while building this route, nil if no error.
+// The route with errors is not registered by Func, see Mux.Validate.
+func (rt *Route) Err() error {
+	if len(rt.errs) == 0 {
+		return nil
+	}
+	return append(RouteErrors(nil), rt.errs...)
+}
+
+// MustFunc is like Func but panics if the route has some errors
+func (rt *Route) MustFunc(fn routeHandler) {
+	if err := rt.Err(); err != nil {
+		panic(err)
+	}
+	rt.Func(fn)
+}
+
+// Validate return the errors of all the routes which are not registered because of
+// building errors, nil if all routes are registered
+func (mux *Mux) Validate() error {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	if len(mux.routeErrs) == 0 {
+		return nil
+	}
+	return append(RouteErrors(nil), mux.routeErrs...)
+}
+
+// addError record a building error of this route
+func (rt *Route) addError(field, pattern string, position int, err error) {
+	rt.errs = append(rt.errs, &RouteError{
+		Field:    field,
+		Pattern:  pattern,
+		Position: position,
+		Err:      err,
+	})
+}
+
+// hasError report whether this route has some errors of the builder method
+func (rt *Route) hasError(field string) bool {
+	for _, err := range rt.errs {
+		if err.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectInvalid record the errors of a route which isn't registered, and report whether it has errors.
+// The errors inherited from a building route are recorded once.
+func (mux *Mux) rejectInvalid(rt *Route) bool {
+	if len(rt.errs) == 0 {
+		return false
+	}
+
+	if mux.reportedErrs == nil {
+		mux.reportedErrs = make(map[*RouteError]bool)
+	}
+
+	for _, err := range rt.errs {
+		if mux.reportedErrs[err] {
+			continue
+		}
+		mux.reportedErrs[err] = true
+
+		routeErr := *err
+		routeErr.Route = rt.name
+		mux.routeErrs = append(mux.routeErrs, &routeErr)
+		mux.logger.Printf("%v, the route is not registered", &routeErr)
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package sexrt
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteErr(t *testing.T) {
+	rt := new(Route).Path("user", `{id:[}`).Query("page").Header("Accept", `{(}`).
+		Host("{tenant:[a-z]+}.example.com", "{sub?:x}.example.com")
+
+	var errs RouteErrors
+	if !errors.As(rt.Err(), &errs) || len(errs) != 4 {
+		t.Fatal("errors not recorded:", rt.Err())
+	}
+
+	expects := []struct {
+		field    string
+		pattern  string
+		position int
+	}{
+		{"Path", `{id:[}`, 1},
+		{"Query", "page", 0},
+		{"Header", `{(}`, 1},
+		{"Host", "{sub?:x}.example.com", 1},
+	}
+	for i, expect := range expects {
+		if errs[i].Field != expect.field || errs[i].Pattern != expect.pattern || errs[i].Position != expect.position {
+			t.Fatalf("error %d not correct: %v", i, errs[i])
+		}
+	}
+
+	var routeErr *RouteError
+	if !errors.As(rt.Err(), &routeErr) || routeErr.Field != "Path" {
+		t.Fatal("RouteError not found")
+	}
+
+	if new(Route).Path("user", `{id:\d+}`).Query("page", `{page:\d+}`).Err() != nil {
+		t.Fatal("valid route with error")
+	}
+
+	// the position is in the arguments of the call
+	paths := []struct {
+		rt       *Route
+		pattern  string
+		position int
+	}{
+		{new(Route).Path("list").Path(`{page?:\d+}`, "x"), "x", 1},
+		{new(Route).Path("files", `{rest:*}`).Path("x", "y"), "x", 0},
+		{new(Route).Path("files").Path(`{rest:*}`, "x"), "{rest:*}", 0},
+	}
+	for _, path := range paths {
+		if !errors.As(path.rt.Err(), &routeErr) || routeErr.Pattern != path.pattern || routeErr.Position != path.position {
+			t.Fatal("misplaced segment error not correct:", path.rt.Err())
+		}
+	}
+}
+
+func TestMuxValidate(t *testing.T) {
+	mux := NewMux()
+
+	buf := new(bytes.Buffer)
+	mux.SetLogger(log.New(buf, "", 0))
+
+	mux.NewRoute().Path("ok").Func(testHandler)
+	mux.NewRoute().Name("user").Path("user", `{id:[}`).Func(testHandler)
+	mux.NewRoute().Path("search").Query("q").Func(testHandler)
+
+	if err := mux.Validate(); err == nil || len(err.(RouteErrors)) != 2 {
+		t.Fatal("errors not reported:", err)
+	} else if msg := err.Error(); !strings.Contains(msg, `sexrt: route "user": Path argument 1 "{id:[}"`) ||
+		!strings.Contains(msg, `sexrt: Query argument 0 "q": missing value of the pair`) {
+		t.Fatal("error message not correct:", msg)
+	}
+	if !strings.Contains(buf.String(), `route "user"`) {
+		t.Fatal("error not logged:", buf.String())
+	}
+
+	// the errors shared by the subrouters are reported once
+	g := mux.NewRoute().Path("group").Query("page")
+	g.Subrouter().Path("a").Func(testHandler)
+	g.Subrouter().Path("b").Func(testHandler)
+	if err := mux.Validate(); err == nil || len(err.(RouteErrors)) != 3 {
+		t.Fatal("shared errors not reported once:", err)
+	}
+
+	// the invalid routes are not registered
+	if len(mux.Routes()) != 1 {
+		t.Fatal("invalid routes registered")
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/search?q=x", nil))
+	if w.Code != 404 {
+		t.Fatal("/search: invalid route matched")
+	}
+
+	defer func() {
+		if _, ok := recover().(RouteErrors); !ok {
+			t.Fatal("MustFunc not panic")
+		}
+	}()
+	mux.NewRoute().Path(`{rest:*}`, "x").MustFunc(testHandler)
+}
+
+func TestMuxDuplicateName(t *testing.T) {
+	mux := NewMux()
+	mux.SetLogger(log.New(ioutil.Discard, "", 0))
+
+	mux.NewRoute().Name("user").Path("user").Func(testHandler)
+	mux.NewRoute().Name("user").Path("member").Func(testHandler)
+
+	var routeErr *RouteError
+	if !errors.As(mux.Validate(), &routeErr) || routeErr.Field != "Name" || !errors.Is(routeErr, errDuplicateName) {
+		t.Fatal("duplicate name not reported:", mux.Validate())
+	}
+	if u, err := mux.URL("user", nil); err != nil || u != "/user" {
+		t.Fatal("the first route taken over:", u, err)
+	}
+
+	// the name can be used again after removed
+	if !mux.Remove("user") {
+		t.Fatal("route not removed")
+	}
+	mux.NewRoute().Name("user").Path("member").Func(testHandler)
+	if u, err := mux.URL("user", nil); err != nil || u != "/member" {
+		t.Fatal("name not reused:", u, err)
+	}
+}